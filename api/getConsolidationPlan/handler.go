@@ -0,0 +1,69 @@
+package getconsolidationplan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// return lambda handler serving consolidation plan
+func NewHandler(svc service.Service, repo repository.IpConfigRepository, policyRepo repository.PolicyRepository, hostRepo repository.HostRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		plan, svcErr := getConsolidationPlan(svc, repo, policyRepo, hostRepo, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		return service.SuccessResponse(plan), nil
+	}
+}
+
+// plan emptying the hosts the inefficient servers API reports for the same request
+func getConsolidationPlan(svc service.Service, repo repository.IpConfigRepository, policyRepo repository.PolicyRepository, hostRepo repository.HostRepository, req events.APIGatewayV2HTTPRequest) (models.ConsolidationPlan, errorlib.Error) {
+	capacity, svcErr := getCapacity(req)
+	if svcErr != nil {
+		return models.ConsolidationPlan{}, svcErr
+	}
+	// get rule from request or configuration
+	rule, svcErr := svc.GetRule(req)
+	if svcErr != nil {
+		return models.ConsolidationPlan{}, svcErr
+	}
+	policies, svcErr := policyRepo.Load()
+	if svcErr != nil {
+		return models.ConsolidationPlan{}, svcErr
+	}
+	hosts, svcErr := hostRepo.Load()
+	if svcErr != nil {
+		return models.ConsolidationPlan{}, svcErr
+	}
+	// get server data from storage
+	ipConfig, svcErr := repo.Load()
+	if svcErr != nil {
+		return models.ConsolidationPlan{}, svcErr
+	}
+	return analyzer.ConsolidationPlan(ipConfig, capacity, analyzer.Options{
+		Rule:       rule,
+		Policies:   policies,
+		Hosts:      hosts,
+		HostFilter: filter.ParseHost(req.QueryStringParameters),
+	}), nil
+}
+
+// get maximum active MTAs per host from query parameter
+func getCapacity(req events.APIGatewayV2HTTPRequest) (int, errorlib.Error) {
+	capacity, err := strconv.Atoi(req.QueryStringParameters[constants.CapacityKey])
+	if err != nil || capacity < 1 {
+		return 0, errorlib.New(errors.New("invalid capacity query parameter. Please provide a positive integer"), http.StatusBadRequest)
+	}
+	return capacity, nil
+}
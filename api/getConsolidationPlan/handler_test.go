@@ -0,0 +1,183 @@
+package getconsolidationplan
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	dummyS3 "github.com/mta-hosting-optimizer/lib/aws/s3/dummy"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockServerJsonData = `[{
+	"ip":"DummyIP1",
+	"hostname":"DummyHostname1",
+	"active": true
+},{
+	"ip":"DummyIP2",
+	"hostname":"DummyHostname2",
+	"active": true
+},{
+	"ip":"DummyIP3",
+	"hostname":"DummyHostname2",
+	"active": true
+},{
+	"ip":"DummyIP4",
+	"hostname":"DummyHostname3",
+	"active": false
+}]`
+
+// make repository reading server data through the dummy S3 service
+func newS3Repository(svc service.Service) repository.IpConfigRepository {
+	return repository.NewIpConfigRepository(storage.NewS3Store(svc, svc.Config.Bucket), svc.Config.Key)
+}
+
+// make repository holding the given policies in memory
+func newPolicyRepository(policies ...models.Policy) repository.PolicyRepository {
+	repo := repository.NewPolicyRepository(storage.NewMemoryStore(), "policies.json")
+	if len(policies) > 0 {
+		repo.Save(policies)
+	}
+	return repo
+}
+
+// make repository holding the given host metadata in memory
+func newHostRepository(hosts ...models.Host) repository.HostRepository {
+	repo := repository.NewHostRepository(storage.NewMemoryStore(), "hosts.json")
+	repo.Update(func([]models.Host) ([]models.Host, error) {
+		return hosts, nil
+	})
+	return repo
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func Test_getConsolidationPlan_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "1", "capacity": "3"},
+	}
+	result, err := getConsolidationPlan(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.ConsolidationPlan{
+		Threshold:           intPtr(1),
+		Rule:                models.Rule{MaxActive: intPtr(1)},
+		Capacity:            3,
+		DecommissionCount:   2,
+		DecommissionedHosts: []string{"DummyHostname3", "DummyHostname1"},
+		UnresolvedHosts:     []string{},
+		Moves: []models.Move{
+			{Ip: "DummyIP1", From: "DummyHostname1", To: "DummyHostname2"},
+		},
+	})
+}
+
+func Test_getConsolidationPlan_InvalidCapacity_Fail(t *testing.T) {
+	svc := service.Service{}
+	for _, capacity := range []string{"", "dummy", "0"} {
+		req := events.APIGatewayV2HTTPRequest{
+			QueryStringParameters: map[string]string{"threshold": "1", "capacity": capacity},
+		}
+		result, err := getConsolidationPlan(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), req)
+		assert.Equal(t, result, models.ConsolidationPlan{})
+		assert.Equal(t, err.StatusCode(), 400)
+	}
+}
+
+func Test_getConsolidationPlan_MockDataNotFound_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "1", "capacity": "3"},
+	}
+	result, err := getConsolidationPlan(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), req)
+	assert.Equal(t, result, models.ConsolidationPlan{})
+	assert.Equal(t, err.Error(), "server information not found")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_getConsolidationPlan_RuleAndHosts_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "mta-marketing-1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "mta-marketing-1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "mta-marketing-1", Active: false},
+		{Ip: "10.0.0.4", Hostname: "mta-transactional-1", Active: true},
+		{Ip: "10.0.0.5", Hostname: "mta-transactional-2", Active: true},
+		{Ip: "10.0.0.6", Hostname: "mta-transactional-2", Active: true},
+		{Ip: "10.0.0.7", Hostname: "mta-other-1", Active: true},
+	})
+	policyRepo := newPolicyRepository(models.Policy{Name: "transactional", Hostname: "mta-transactional-*", Rule: models.Rule{MaxActive: intPtr(0)}})
+	hostRepo := newHostRepository(
+		models.Host{Hostname: "mta-marketing-1", Datacenter: "dc1"},
+		models.Host{Hostname: "mta-transactional-1", Datacenter: "dc1"},
+		models.Host{Hostname: "mta-transactional-2", Datacenter: "dc1"},
+	)
+	svc := service.Service{}
+	svc.Config.Threshold = 2
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected []models.Move
+	}{
+		{
+			// the policy keeps transactional hosts, the other hosts are emptied onto them
+			name:   "Policies",
+			params: map[string]string{"capacity": "3"},
+			expected: []models.Move{
+				{Ip: "10.0.0.1", From: "mta-marketing-1", To: "mta-transactional-2"},
+				{Ip: "10.0.0.7", From: "mta-other-1", To: "mta-transactional-1"},
+			},
+		},
+		{
+			// the ratio rule marks only the marketing host, emptied onto the fullest host
+			name:     "Rule",
+			params:   map[string]string{"capacity": "3", "ratio": "0.5"},
+			expected: []models.Move{{Ip: "10.0.0.1", From: "mta-marketing-1", To: "mta-transactional-2"}},
+		},
+		{
+			// hosts outside the datacenter neither give nor receive MTAs
+			name:     "Host filter",
+			params:   map[string]string{"capacity": "2", "datacenter": "dc1"},
+			expected: []models.Move{{Ip: "10.0.0.1", From: "mta-marketing-1", To: "mta-transactional-1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params}
+			result, err := getConsolidationPlan(svc, repo, policyRepo, hostRepo, req)
+			assert.Nil(t, err)
+			assert.Equal(t, result.Moves, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package getinefficientservers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	"github.com/mta-hosting-optimizer/lib/codec"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// return lambda handler serving inefficient servers as JSON, CSV or NDJSON
+func NewHandler(svc service.Service, repo repository.IpConfigRepository, policyRepo repository.PolicyRepository, hostRepo repository.HostRepository, indexRepo repository.IndexRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		format, svcErr := service.GetFormat(req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		if format != codec.FormatJSON {
+			req = detailedRequest(req)
+		}
+		inefficientServers, svcErr := getInefficientServers(svc, repo, policyRepo, hostRepo, indexRepo, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		if len(inefficientServers.Hostnames) == 0 {
+			// a cursor past the last page returns an empty page
+			if req.QueryStringParameters[constants.CursorKey] == "" {
+				svcErr := errorlib.New(errors.New("no inefficient servers found as per rule"), http.StatusNotFound)
+				return service.ErrorResponse(svcErr), nil
+			}
+			inefficientServers.Hostnames = []string{}
+		}
+		if format != codec.FormatJSON {
+			return exportResponse(inefficientServers, format), nil
+		}
+		return service.SuccessResponse(inefficientServers), nil
+	}
+}
+
+// exports list the MTA usage of every server, as in detailed mode
+func detailedRequest(req events.APIGatewayV2HTTPRequest) events.APIGatewayV2HTTPRequest {
+	params := make(map[string]string, len(req.QueryStringParameters)+1)
+	for key, value := range req.QueryStringParameters {
+		params[key] = value
+	}
+	params[constants.DetailedKey] = "true"
+	req.QueryStringParameters = params
+	return req
+}
+
+// encode servers as CSV or NDJSON, the cursor of the next page is returned as header
+func exportResponse(inefficientServers models.ServerResponse, format string) events.APIGatewayV2HTTPResponse {
+	var buf bytes.Buffer
+	var err error
+	if format == codec.FormatCSV {
+		err = codec.WriteServerCSV(&buf, inefficientServers.Servers)
+	} else {
+		err = codec.WriteNDJSON(&buf, inefficientServers.Servers)
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		return service.ErrorResponse(errorlib.New(err, http.StatusInternalServerError))
+	}
+	resp := service.ContentResponse(http.StatusOK, format, buf.Bytes())
+	if inefficientServers.NextCursor != "" {
+		resp.Headers[constants.NextCursorHeader] = inefficientServers.NextCursor
+	}
+	return resp
+}
+
+// group of hosts without the requested metadata
+const unassignedGroup = "unassigned"
+
+func getInefficientServers(svc service.Service, repo repository.IpConfigRepository, policyRepo repository.PolicyRepository, hostRepo repository.HostRepository, indexRepo repository.IndexRepository, req events.APIGatewayV2HTTPRequest) (models.ServerResponse, errorlib.Error) {
+	// get rule from request or configuration
+	rule, svcErr := svc.GetRule(req)
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	detailed, svcErr := isDetailed(req)
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	sortBy, svcErr := getSortKey(req)
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	page, svcErr := pagination.Parse(req.QueryStringParameters[constants.LimitKey], req.QueryStringParameters[constants.CursorKey])
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	groupBy, svcErr := getGroupBy(req)
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	policies, svcErr := policyRepo.Load()
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	hosts, svcErr := hostRepo.Load()
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	// IPs are only held in memory if they are returned
+	servers, svcErr := getServers(repo, indexRepo, detailed)
+	if svcErr != nil {
+		return models.ServerResponse{}, svcErr
+	}
+	result := analyzer.AnalyzeServers(servers, analyzer.Options{
+		Rule:       rule,
+		Policies:   policies,
+		Hosts:      hosts,
+		HostFilter: filter.ParseHost(req.QueryStringParameters),
+	})
+	inefficient := result.Inefficient
+	sortServers(inefficient, sortBy)
+	start, end, nextCursor := page.Bounds(len(inefficient))
+	var inefficientHostnames []string
+	var inefficientServers []models.ServerDetail
+	var hostPolicies map[string]string
+	if len(policies) > 0 {
+		hostPolicies = make(map[string]string)
+	}
+	var groups map[string][]string
+	if groupBy != "" {
+		groups = make(map[string][]string)
+	}
+	for _, server := range inefficient[start:end] {
+		inefficientHostnames = append(inefficientHostnames, server.Hostname)
+		if hostPolicies != nil {
+			hostPolicies[server.Hostname] = server.Policy
+		}
+		if groups != nil {
+			group := groupOf(server, groupBy)
+			groups[group] = append(groups[group], server.Hostname)
+		}
+		if detailed {
+			inefficientServers = append(inefficientServers, server)
+		}
+	}
+	return models.ServerResponse{
+		Hostnames:  inefficientHostnames,
+		Threshold:  rule.MaxActive,
+		Rule:       rule,
+		Policies:   hostPolicies,
+		Groups:     groups,
+		Servers:    inefficientServers,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// get MTA usage of every server from the index, unless IPs are needed or the index was not built
+// or is stale
+func getServers(repo repository.IpConfigRepository, indexRepo repository.IndexRepository, withIps bool) (map[string]*models.ServerDetail, errorlib.Error) {
+	if withIps {
+		return repository.AggregateServers(repo, true)
+	}
+	counts, svcErr := indexRepo.Load()
+	if svcErr != nil {
+		if svcErr.StatusCode() == http.StatusNotFound {
+			return repository.AggregateServers(repo, false)
+		}
+		return nil, svcErr
+	}
+	servers := make(map[string]*models.ServerDetail, len(counts))
+	for _, count := range counts {
+		servers[count.Hostname] = &models.ServerDetail{
+			Hostname:     count.Hostname,
+			ActiveMTAs:   count.ActiveMTAs,
+			InactiveMTAs: count.InactiveMTAs,
+			TotalIps:     count.ActiveMTAs + count.InactiveMTAs,
+		}
+	}
+	return servers, nil
+}
+
+// get sort order from query parameter, defaults to hostname
+func getSortKey(req events.APIGatewayV2HTTPRequest) (string, errorlib.Error) {
+	sortBy, ok := req.QueryStringParameters[constants.SortKey]
+	if !ok {
+		return constants.SortByHostname, nil
+	}
+	switch sortBy {
+	case constants.SortByHostname, constants.SortByActive:
+		return sortBy, nil
+	default:
+		return "", errorlib.New(errors.New("invalid sort query parameter. Please provide hostname or active"), http.StatusBadRequest)
+	}
+}
+
+// get metadata attribute to group servers by from query parameter, empty if servers are not grouped
+func getGroupBy(req events.APIGatewayV2HTTPRequest) (string, errorlib.Error) {
+	groupBy, ok := req.QueryStringParameters[constants.GroupByKey]
+	if !ok {
+		return "", nil
+	}
+	switch groupBy {
+	case constants.GroupByDatacenter, constants.GroupByPool:
+		return groupBy, nil
+	default:
+		return "", errorlib.New(errors.New("invalid groupBy query parameter. Please provide datacenter or pool"), http.StatusBadRequest)
+	}
+}
+
+// get group of server, hosts without the metadata attribute are unassigned
+func groupOf(server models.ServerDetail, groupBy string) string {
+	group := server.Pool
+	if groupBy == constants.GroupByDatacenter {
+		group = server.Datacenter
+	}
+	if group == "" {
+		return unassignedGroup
+	}
+	return group
+}
+
+// sort servers by hostname, or by active MTAs with hostname as tie breaker
+func sortServers(servers []models.ServerDetail, sortBy string) {
+	sort.SliceStable(servers, func(i, j int) bool {
+		if sortBy == constants.SortByActive && servers[i].ActiveMTAs != servers[j].ActiveMTAs {
+			return servers[i].ActiveMTAs < servers[j].ActiveMTAs
+		}
+		return servers[i].Hostname < servers[j].Hostname
+	})
+}
+
+// check if per-host MTA counts are requested
+func isDetailed(req events.APIGatewayV2HTTPRequest) (bool, errorlib.Error) {
+	value, ok := req.QueryStringParameters[constants.DetailedKey]
+	if !ok {
+		return false, nil
+	}
+	detailed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errorlib.New(errors.New("invalid detailed query parameter. Please provide true or false"), http.StatusBadRequest)
+	}
+	return detailed, nil
+}
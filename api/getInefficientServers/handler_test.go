@@ -0,0 +1,652 @@
+package getinefficientservers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	dummyS3 "github.com/mta-hosting-optimizer/lib/aws/s3/dummy"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockServerJsonData = `[{
+	"ip":"DummyIP1",
+	"hostname":"DummyHostname1",
+	"active": true
+},{
+	"ip":"DummyIP2",
+	"hostname":"DummyHostname2",
+	"active": false
+},{
+	"ip":"DummyIP2",
+	"hostname":"DummyHostname2",
+	"active": true
+},{
+	"ip":"DummyIP1",
+	"hostname":"DummyHostname2",
+	"active": true
+},{
+	"ip":"DummyIP3",
+	"hostname":"DummyHostname3",
+	"active": false
+}]`
+
+// make repository reading server data through the dummy S3 service
+func newS3Repository(svc service.Service) repository.IpConfigRepository {
+	return repository.NewIpConfigRepository(storage.NewS3Store(svc, svc.Config.Bucket), svc.Config.Key)
+}
+
+// make repository holding the given policies in memory
+func newPolicyRepository(policies ...models.Policy) repository.PolicyRepository {
+	repo := repository.NewPolicyRepository(storage.NewMemoryStore(), "policies.json")
+	if len(policies) > 0 {
+		repo.Save(policies)
+	}
+	return repo
+}
+
+// make empty index, server data is read from the repository
+func newIndexRepository() repository.IndexRepository {
+	return repository.NewIndexRepository(storage.NewMemoryStore(), "index.json")
+}
+
+// make repository holding the given host metadata in memory
+func newHostRepository(hosts ...models.Host) repository.HostRepository {
+	repo := repository.NewHostRepository(storage.NewMemoryStore(), "hosts.json")
+	repo.Update(func([]models.Host) ([]models.Host, error) {
+		return hosts, nil
+	})
+	return repo
+}
+
+func Test_getInefficientServers_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		expected  models.ServerResponse
+	}{
+		{
+			name:      "Success with threshold as 1",
+			threshold: 1,
+			expected: models.ServerResponse{
+				Hostnames: []string{"DummyHostname1", "DummyHostname3"},
+				Threshold: intPtr(1),
+				Rule:      models.Rule{MaxActive: intPtr(1)},
+			},
+		},
+		{
+			name:      "Success with threshold as 2",
+			threshold: 2,
+			expected: models.ServerResponse{
+				Hostnames: []string{"DummyHostname1", "DummyHostname2", "DummyHostname3"},
+				Threshold: intPtr(2),
+				Rule:      models.Rule{MaxActive: intPtr(2)},
+			},
+		},
+		{
+			name:      "Success with threshold as 0",
+			threshold: 0,
+			expected: models.ServerResponse{
+				Hostnames: []string{"DummyHostname3"},
+				Threshold: intPtr(0),
+				Rule:      models.Rule{MaxActive: intPtr(0)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.Config.Threshold = tt.threshold
+			result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), events.APIGatewayV2HTTPRequest{})
+			assert.Nil(t, err)
+			assert.Equal(t, result, tt.expected)
+		})
+	}
+
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func Test_getInefficientServers_ThresholdQueryParam_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	svc.Config.Threshold = 2
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "0"},
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.ServerResponse{
+		Hostnames: []string{"DummyHostname3"},
+		Threshold: intPtr(0),
+		Rule:      models.Rule{MaxActive: intPtr(0)},
+	})
+}
+
+func Test_getInefficientServers_InvalidThresholdQueryParam_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	for _, threshold := range []string{"dummy", "-1", ""} {
+		req := events.APIGatewayV2HTTPRequest{
+			QueryStringParameters: map[string]string{"threshold": threshold},
+		}
+		result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+		assert.Equal(t, result, models.ServerResponse{})
+		assert.Equal(t, err.StatusCode(), 400)
+	}
+}
+
+func Test_getInefficientServers_Detailed_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "0", "detailed": "true"},
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.ServerResponse{
+		Hostnames: []string{"DummyHostname3"},
+		Threshold: intPtr(0),
+		Rule:      models.Rule{MaxActive: intPtr(0)},
+		Servers: []models.ServerDetail{
+			{
+				Hostname:     "DummyHostname3",
+				ActiveMTAs:   0,
+				InactiveMTAs: 1,
+				TotalIps:     1,
+				Ips:          []string{"DummyIP3"},
+			},
+		},
+	})
+}
+
+func Test_getInefficientServers_InvalidDetailedQueryParam_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "0", "detailed": "dummy"},
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Equal(t, result, models.ServerResponse{})
+	assert.Equal(t, err.Error(), "invalid detailed query parameter. Please provide true or false")
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_getInefficientServers_SortAndPaginate_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "2", "sort": "active", "limit": "2"},
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Hostnames, []string{"DummyHostname3", "DummyHostname1"})
+	assert.Equal(t, result.NextCursor, pagination.EncodeCursor(2))
+
+	req.QueryStringParameters["cursor"] = result.NextCursor
+	result, err = getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Hostnames, []string{"DummyHostname2"})
+	assert.Equal(t, result.NextCursor, "")
+}
+
+func Test_getInefficientServers_InvalidSortQueryParam_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "2", "sort": "dummy"},
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Equal(t, result, models.ServerResponse{})
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_getInefficientServers_MockDataNotFound_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+		},
+		Sess: sess,
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, result, models.ServerResponse{})
+	assert.Equal(t, err.Error(), "server information not found")
+
+}
+func Test_getInefficientServers_GetS3ObjectError_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{}, errors.New("get s3 object fail")
+			},
+		},
+		Sess: sess,
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, result, models.ServerResponse{})
+	assert.Equal(t, err.Error(), "get s3 object fail")
+	assert.Equal(t, err.StatusCode(), 500)
+
+}
+func Test_getInefficientServers_InvalidModelStructure_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString("Invalid Data")),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	result, err := getInefficientServers(svc, newS3Repository(svc), newPolicyRepository(), newHostRepository(), newIndexRepository(), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, result, models.ServerResponse{})
+	assert.Error(t, err)
+	assert.Equal(t, err.StatusCode(), 500)
+
+}
+
+func Test_getInefficientServers_Rule_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.4", Hostname: "DummyHostname2", Active: false},
+		{Ip: "10.0.0.5", Hostname: "DummyHostname2", Active: false},
+		{Ip: "10.0.0.6", Hostname: "DummyHostname2", Active: false},
+	})
+	svc := service.Service{}
+	svc.Config.Threshold = 1
+	ratio := 0.25
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected []string
+		rule     models.Rule
+	}{
+		{
+			name:     "Configured rule",
+			params:   map[string]string{},
+			expected: []string{"DummyHostname1", "DummyHostname2"},
+			rule:     models.Rule{MaxActive: intPtr(1)},
+		},
+		{
+			name:     "Ratio",
+			params:   map[string]string{"ratio": "0.25"},
+			expected: []string{"DummyHostname2"},
+			rule:     models.Rule{MaxActiveRatio: &ratio},
+		},
+		{
+			name:     "Threshold and minimum IPs",
+			params:   map[string]string{"threshold": "1", "minIps": "3", "match": "and"},
+			expected: []string{"DummyHostname2"},
+			rule:     models.Rule{MaxActive: intPtr(1), MinTotalIps: intPtr(3), Match: models.MatchAll},
+		},
+		{
+			name:     "Threshold or ratio",
+			params:   map[string]string{"threshold": "0", "ratio": "0.25", "match": "or"},
+			expected: []string{"DummyHostname2"},
+			rule:     models.Rule{MaxActive: intPtr(0), MaxActiveRatio: &ratio, Match: models.MatchAny},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params}
+			result, err := getInefficientServers(svc, repo, newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+			assert.Nil(t, err)
+			assert.Equal(t, result.Hostnames, tt.expected)
+			assert.Equal(t, result.Rule, tt.rule)
+		})
+	}
+}
+
+func Test_getInefficientServers_Sharded_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json", repository.WithShards(4, 2))
+	var ipConfig []models.IpConfig
+	for i := 0; i < 20; i++ {
+		// every host has 5 IPs spread across shards, only DummyHostname0 has a single active one
+		ipConfig = append(ipConfig, models.IpConfig{Ip: "10.0.0." + strconv.Itoa(i), Hostname: "DummyHostname" + strconv.Itoa(i%4), Active: i%4 != 0 || i == 0})
+	}
+	repo.Append(ipConfig...)
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"threshold": "1", "detailed": "true"},
+	}
+	result, err := getInefficientServers(service.Service{}, repo, newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Hostnames, []string{"DummyHostname0"})
+	assert.Equal(t, result.Servers[0].ActiveMTAs, 1)
+	assert.Equal(t, result.Servers[0].InactiveMTAs, 4)
+	assert.ElementsMatch(t, result.Servers[0].Ips, []string{"10.0.0.0", "10.0.0.4", "10.0.0.8", "10.0.0.12", "10.0.0.16"})
+}
+
+func Test_getInefficientServers_Index_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	indexRepo := repository.NewIndexRepository(store, "index.json")
+	repo := repository.NewIpConfigRepository(store, "ipConfig.json", repository.WithIndex(indexRepo))
+	repo.Append(
+		models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: true},
+		models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+	)
+	// the index is built once the data is created
+	_, svcErr := repository.RebuildIndex(repo, indexRepo)
+	assert.Nil(t, svcErr)
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected []string
+	}{
+		{name: "Index", params: map[string]string{"threshold": "1"}, expected: []string{"DummyHostname1"}},
+		{name: "Detailed", params: map[string]string{"threshold": "1", "detailed": "true"}, expected: []string{"DummyHostname1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params}
+			result, err := getInefficientServers(service.Service{}, repo, newPolicyRepository(), newHostRepository(), indexRepo, req)
+			assert.Nil(t, err)
+			assert.Equal(t, result.Hostnames, tt.expected)
+		})
+	}
+
+	// counts are read from server data while the index is stale
+	indexRepo.Invalidate()
+	repo.Append(models.IpConfig{Ip: "10.0.0.4", Hostname: "DummyHostname3", Active: false})
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"threshold": "1"}}
+	result, err := getInefficientServers(service.Service{}, repo, newPolicyRepository(), newHostRepository(), indexRepo, req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Hostnames, []string{"DummyHostname1", "DummyHostname3"})
+}
+
+func Test_getInefficientServers_InvalidRule_Fail(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true}})
+	for _, params := range []map[string]string{
+		{"ratio": "1.5"},
+		{"minIps": "-1"},
+		{"match": "xor"},
+	} {
+		_, err := getInefficientServers(service.Service{}, repo, newPolicyRepository(), newHostRepository(), newIndexRepository(), events.APIGatewayV2HTTPRequest{QueryStringParameters: params})
+		assert.Equal(t, err.StatusCode(), 400)
+	}
+}
+
+func Test_getInefficientServers_Policies_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "mta-marketing-1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "mta-marketing-1", Active: true},
+		{Ip: "10.0.0.3", Hostname: "mta-marketing-1", Active: false},
+		{Ip: "10.0.0.4", Hostname: "mta-transactional-1", Active: true},
+		{Ip: "10.0.0.5", Hostname: "mta-transactional-1", Active: true},
+		{Ip: "10.0.0.6", Hostname: "mta-other-1", Active: true},
+	})
+	policyRepo := newPolicyRepository(
+		models.Policy{Name: "marketing", Hostname: "mta-marketing-*", Rule: models.Rule{MaxActive: intPtr(2)}},
+		models.Policy{Name: "transactional", Hostname: "mta-transactional-*", Rule: models.Rule{MaxActive: intPtr(0)}},
+	)
+	svc := service.Service{}
+	svc.Config.Threshold = 1
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"detailed": "true"}}
+	result, err := getInefficientServers(svc, repo, policyRepo, newHostRepository(), newIndexRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Hostnames, []string{"mta-marketing-1", "mta-other-1"})
+	assert.Equal(t, result.Policies, map[string]string{"mta-marketing-1": "marketing", "mta-other-1": "default"})
+	assert.Equal(t, result.Servers[0].Policy, "marketing")
+	assert.Equal(t, result.Servers[1].Policy, "default")
+}
+
+func Test_getInefficientServers_HostMetadata_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname3", Active: false},
+		{Ip: "10.0.0.4", Hostname: "DummyHostname4", Active: true},
+	})
+	hostRepo := newHostRepository(
+		models.Host{Hostname: "DummyHostname1", Datacenter: "dc1", Pool: "marketing", Capacity: 10, Tags: []string{"warm-up"}},
+		models.Host{Hostname: "DummyHostname2", Datacenter: "dc2", Pool: "marketing"},
+		models.Host{Hostname: "DummyHostname3", Datacenter: "dc1", Pool: "transactional"},
+	)
+	policyRepo := newPolicyRepository(
+		models.Policy{Name: "warm-up", Tags: []string{"warm-up"}, Rule: models.Rule{MaxActive: intPtr(0)}},
+	)
+	svc := service.Service{}
+	svc.Config.Threshold = 1
+	tests := []struct {
+		name      string
+		params    map[string]string
+		hostnames []string
+		groups    map[string][]string
+	}{
+		{
+			name:      "No filter",
+			params:    map[string]string{},
+			hostnames: []string{"DummyHostname2", "DummyHostname3", "DummyHostname4"},
+		},
+		{
+			name:      "Datacenter",
+			params:    map[string]string{"datacenter": "dc1"},
+			hostnames: []string{"DummyHostname3"},
+		},
+		{
+			// the tagged host is evaluated against the warm-up policy
+			name:      "Pool",
+			params:    map[string]string{"pool": "marketing"},
+			hostnames: []string{"DummyHostname2"},
+		},
+		{
+			name:      "Tag",
+			params:    map[string]string{"tag": "warm-up"},
+			hostnames: nil,
+		},
+		{
+			name:      "Group by pool",
+			params:    map[string]string{"groupBy": "pool"},
+			hostnames: []string{"DummyHostname2", "DummyHostname3", "DummyHostname4"},
+			groups: map[string][]string{
+				"marketing":     {"DummyHostname2"},
+				"transactional": {"DummyHostname3"},
+				"unassigned":    {"DummyHostname4"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params}
+			result, err := getInefficientServers(svc, repo, policyRepo, hostRepo, newIndexRepository(), req)
+			assert.Nil(t, err)
+			assert.Equal(t, result.Hostnames, tt.hostnames)
+			assert.Equal(t, result.Groups, tt.groups)
+		})
+	}
+}
+
+func Test_getInefficientServers_InvalidGroupBy_Fail(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true}})
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"groupBy": "dummy"}}
+	_, err := getInefficientServers(service.Service{}, repo, newPolicyRepository(), newHostRepository(), newIndexRepository(), req)
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_NewHandler_Export_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save([]models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.4", Hostname: "DummyHostname3", Active: true},
+		{Ip: "10.0.0.5", Hostname: "DummyHostname3", Active: true},
+	})
+	hostRepo := newHostRepository(models.Host{Hostname: "DummyHostname1", Datacenter: "dc1", Pool: "marketing"})
+	svc := service.Service{}
+	svc.Config.Threshold = 1
+	handler := NewHandler(svc, repo, newPolicyRepository(), hostRepo, newIndexRepository())
+
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"format": "csv", "limit": "1"}}
+	resp, err := handler(context.Background(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.Equal(t, resp.Headers["Content-Type"], "text/csv")
+	assert.Equal(t, resp.Headers["X-Next-Cursor"], pagination.EncodeCursor(1))
+	assert.Equal(t, resp.Body, "hostname,activeMTAs,inactiveMTAs,totalIps,policy,datacenter,pool\nDummyHostname1,1,1,2,,dc1,marketing\n")
+	// the request parameters are left unchanged
+	assert.Equal(t, len(req.QueryStringParameters), 2)
+
+	req = events.APIGatewayV2HTTPRequest{Headers: map[string]string{"accept": "application/x-ndjson"}, QueryStringParameters: map[string]string{"sort": "active"}}
+	resp, err = handler(context.Background(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.Headers["Content-Type"], "application/x-ndjson")
+	assert.Equal(t, resp.Body, `{"hostname":"DummyHostname1","activeMTAs":1,"inactiveMTAs":1,"totalIps":2,"ips":["10.0.0.1","10.0.0.2"],"datacenter":"dc1","pool":"marketing"}`+"\n"+
+		`{"hostname":"DummyHostname2","activeMTAs":1,"inactiveMTAs":0,"totalIps":1,"ips":["10.0.0.3"]}`+"\n")
+
+	resp, err = handler(context.Background(), events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"format": "xml"}})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 400)
+}
+
+func Test_NewHandler_Pagination_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	var ipConfig []models.IpConfig
+	for i := 0; i < pagination.DefaultLimit+1; i++ {
+		ipConfig = append(ipConfig, models.IpConfig{Ip: "10.0.0." + strconv.Itoa(i), Hostname: "DummyHostname" + strconv.Itoa(i)})
+	}
+	repo.Save(ipConfig)
+	handler := NewHandler(service.Service{}, repo, newPolicyRepository(), newHostRepository(), newIndexRepository())
+
+	// unpaged requests return all hosts
+	resp, err := handler(context.Background(), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+	var result models.ServerResponse
+	json.Unmarshal([]byte(resp.Body), &result)
+	assert.Equal(t, len(result.Hostnames), len(ipConfig))
+	assert.Equal(t, result.NextCursor, "")
+
+	// a cursor without limit returns the default page size
+	resp, err = handler(context.Background(), events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"cursor": pagination.EncodeCursor(0)}})
+	assert.Nil(t, err)
+	result = models.ServerResponse{}
+	json.Unmarshal([]byte(resp.Body), &result)
+	assert.Equal(t, len(result.Hostnames), pagination.DefaultLimit)
+	assert.Equal(t, result.NextCursor, pagination.EncodeCursor(pagination.DefaultLimit))
+
+	// a cursor past the last page returns an empty page
+	resp, err = handler(context.Background(), events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"cursor": pagination.EncodeCursor(len(ipConfig))}})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.Equal(t, resp.Body, `{"hostnames":[],"threshold":0,"rule":{"maxActive":0,"match":""}}`)
+}
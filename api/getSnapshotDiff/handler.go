@@ -0,0 +1,76 @@
+package getsnapshotdiff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// return lambda handler serving changes of server data between two snapshots
+func NewHandler(svc service.Service, snapshots repository.SnapshotRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		diff, svcErr := getSnapshotDiff(svc, snapshots, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		return service.SuccessResponse(diff), nil
+	}
+}
+
+// compare snapshots of from and to query parameters. To defaults to the latest snapshot,
+// from to the snapshot taken before to
+func getSnapshotDiff(svc service.Service, snapshots repository.SnapshotRepository, req events.APIGatewayV2HTTPRequest) (models.SnapshotDiff, errorlib.Error) {
+	// get rule from request or configuration
+	rule, svcErr := svc.GetRule(req)
+	if svcErr != nil {
+		return models.SnapshotDiff{}, svcErr
+	}
+	snapshotList, svcErr := snapshots.List()
+	if svcErr != nil {
+		return models.SnapshotDiff{}, svcErr
+	}
+	to, svcErr := findSnapshot(snapshotList, req.QueryStringParameters[constants.ToKey], len(snapshotList)-1)
+	if svcErr != nil {
+		return models.SnapshotDiff{}, svcErr
+	}
+	from, svcErr := findSnapshot(snapshotList, req.QueryStringParameters[constants.FromKey], to-1)
+	if svcErr != nil {
+		return models.SnapshotDiff{}, svcErr
+	}
+	fromIpConfig, svcErr := snapshots.Load(snapshotList[from].Id)
+	if svcErr != nil {
+		return models.SnapshotDiff{}, svcErr
+	}
+	toIpConfig, svcErr := snapshots.Load(snapshotList[to].Id)
+	if svcErr != nil {
+		return models.SnapshotDiff{}, svcErr
+	}
+	diff := analyzer.Diff(fromIpConfig, toIpConfig, rule)
+	diff.From = snapshotList[from]
+	diff.To = snapshotList[to]
+	return diff, nil
+}
+
+// get index of snapshot with the given id, or the default index if no id is given
+func findSnapshot(snapshotList []models.Snapshot, id string, defaultIndex int) (int, errorlib.Error) {
+	if id == "" {
+		if defaultIndex < 0 {
+			return 0, errorlib.New(errors.New("at least two snapshots are required to compare"), http.StatusNotFound)
+		}
+		return defaultIndex, nil
+	}
+	for i, snapshot := range snapshotList {
+		if snapshot.Id == id {
+			return i, nil
+		}
+	}
+	return 0, errorlib.New(errors.New("snapshot not found for "+id), http.StatusNotFound)
+}
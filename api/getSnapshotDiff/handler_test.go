@@ -0,0 +1,98 @@
+package getsnapshotdiff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockSnapshots = [][]models.IpConfig{
+	{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: true},
+	},
+	{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+	},
+	{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+	},
+}
+
+var mockIds = []string{"20240101T000000.000000000Z", "20240101T010000.000000000Z", "20240101T020000.000000000Z"}
+
+// make snapshot repository holding mock snapshots in memory, one hour apart
+func newMockSnapshots() repository.SnapshotRepository {
+	snapshots := repository.NewSnapshotRepository(storage.NewMemoryStore(), "snapshots/", 0)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, ipConfig := range mockSnapshots {
+		snapshots.Save(takenAt.Add(time.Duration(i)*time.Hour), ipConfig)
+	}
+	return snapshots
+}
+
+func newService() service.Service {
+	return service.Service{Config: config.Config{Threshold: 1, Match: models.MatchAll}}
+}
+
+func Test_getSnapshotDiff_Latest_Success(t *testing.T) {
+	result, err := getSnapshotDiff(newService(), newMockSnapshots(), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, result.From.Id, mockIds[1])
+	assert.Equal(t, result.To.Id, mockIds[2])
+	assert.Equal(t, result.AddedIps, []models.IpConfig{{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true}})
+	assert.Equal(t, result.BecameInefficient, []string{"DummyHostname2"})
+	assert.Equal(t, result.NoLongerInefficient, []string{})
+}
+
+func Test_getSnapshotDiff_Ids_Success(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"from": mockIds[0], "to": mockIds[1]}}
+	result, err := getSnapshotDiff(newService(), newMockSnapshots(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.ActiveChanged, []models.ActiveChange{{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false}})
+	assert.Equal(t, result.BecameInefficient, []string{"DummyHostname1"})
+
+	// the inefficient set follows the rule of the request
+	req.QueryStringParameters["threshold"] = "2"
+	result, err = getSnapshotDiff(newService(), newMockSnapshots(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.BecameInefficient, []string{})
+}
+
+func Test_getSnapshotDiff_Fail(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     map[string]string
+		statusCode int
+	}{
+		{name: "Unknown from", params: map[string]string{"from": "20230101T000000.000000000Z"}, statusCode: 404},
+		{name: "Unknown to", params: map[string]string{"to": "Invalid"}, statusCode: 404},
+		{name: "No previous snapshot", params: map[string]string{"to": mockIds[0]}, statusCode: 404},
+		{name: "Invalid threshold", params: map[string]string{"threshold": "-1"}, statusCode: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getSnapshotDiff(newService(), newMockSnapshots(), events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params})
+			assert.Equal(t, err.StatusCode(), tt.statusCode)
+		})
+	}
+}
+
+func Test_NewHandler_NoSnapshots_Fail(t *testing.T) {
+	handler := NewHandler(newService(), repository.NewSnapshotRepository(storage.NewMemoryStore(), "snapshots/", 0))
+	resp, err := handler(context.Background(), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 404)
+	assert.Equal(t, resp.Body, `{"error":"at least two snapshots are required to compare","statusCode":404}`)
+}
@@ -0,0 +1,77 @@
+package getsubnetutilisation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// share of active MTAs below which a subnet is flagged if no ratio is requested
+const defaultRatio = 0.5
+
+// return lambda handler serving active and inactive MTAs per subnet
+func NewHandler(repo repository.IpConfigRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		report, svcErr := getSubnetUtilisation(repo, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		return service.SuccessResponse(report), nil
+	}
+}
+
+func getSubnetUtilisation(repo repository.IpConfigRepository, req events.APIGatewayV2HTTPRequest) (models.SubnetReport, errorlib.Error) {
+	prefixV4, svcErr := getPrefix(req, constants.PrefixKey, analyzer.DefaultPrefixV4, 32)
+	if svcErr != nil {
+		return models.SubnetReport{}, svcErr
+	}
+	prefixV6, svcErr := getPrefix(req, constants.Prefix6Key, analyzer.DefaultPrefixV6, 128)
+	if svcErr != nil {
+		return models.SubnetReport{}, svcErr
+	}
+	ratio, svcErr := getRatio(req)
+	if svcErr != nil {
+		return models.SubnetReport{}, svcErr
+	}
+	// get server data from storage
+	ipConfig, svcErr := repo.Load()
+	if svcErr != nil {
+		return models.SubnetReport{}, svcErr
+	}
+	return analyzer.SubnetUtilisation(ipConfig, prefixV4, prefixV6, ratio), nil
+}
+
+// get subnet prefix length from query parameter
+func getPrefix(req events.APIGatewayV2HTTPRequest, key string, defaultBits int, maxBits int) (int, errorlib.Error) {
+	value, ok := req.QueryStringParameters[key]
+	if !ok {
+		return defaultBits, nil
+	}
+	bits, err := strconv.Atoi(value)
+	if err != nil || bits < 0 || bits > maxBits {
+		return 0, errorlib.New(errors.New("invalid "+key+" query parameter. Please provide an integer between 0 and "+strconv.Itoa(maxBits)), http.StatusBadRequest)
+	}
+	return bits, nil
+}
+
+// get minimum share of active MTAs from query parameter
+func getRatio(req events.APIGatewayV2HTTPRequest) (float64, errorlib.Error) {
+	value, ok := req.QueryStringParameters[constants.RatioKey]
+	if !ok {
+		return defaultRatio, nil
+	}
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 0, errorlib.New(errors.New("invalid ratio query parameter. Please provide a number between 0 and 1"), http.StatusBadRequest)
+	}
+	return ratio, nil
+}
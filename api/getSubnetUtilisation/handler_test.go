@@ -0,0 +1,68 @@
+package getsubnetutilisation
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockIpConfig = []models.IpConfig{
+	{Ip: "10.0.1.1", Hostname: "DummyHostname1", Active: true},
+	{Ip: "10.0.1.2", Hostname: "DummyHostname1", Active: false},
+	{Ip: "10.0.1.3", Hostname: "DummyHostname2", Active: false},
+	{Ip: "10.0.2.1", Hostname: "DummyHostname2", Active: true},
+}
+
+// make repository holding mock data in memory
+func newMockRepository() repository.IpConfigRepository {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save(mockIpConfig)
+	return repo
+}
+
+func Test_getSubnetUtilisation_Success(t *testing.T) {
+	result, err := getSubnetUtilisation(newMockRepository(), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, result.Ratio, 0.5)
+	assert.Equal(t, result.FlaggedSubnets, []string{"10.0.1.0/24"})
+	assert.Equal(t, len(result.Subnets), 2)
+}
+
+func Test_getSubnetUtilisation_Query_Success(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"prefix": "16", "ratio": "0.25"},
+	}
+	result, err := getSubnetUtilisation(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Subnets, []models.SubnetDetail{
+		{Subnet: "10.0.0.0/16", ActiveMTAs: 2, InactiveMTAs: 2, TotalIps: 4, ActiveRatio: 0.5},
+	})
+	assert.Equal(t, result.FlaggedSubnets, []string{})
+}
+
+func Test_getSubnetUtilisation_InvalidQuery_Fail(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+	}{
+		{name: "Prefix", params: map[string]string{"prefix": "33"}},
+		{name: "IPv6 prefix", params: map[string]string{"prefix6": "dummy"}},
+		{name: "Ratio", params: map[string]string{"ratio": "1.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getSubnetUtilisation(newMockRepository(), events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params})
+			assert.Equal(t, err.StatusCode(), 400)
+		})
+	}
+}
+
+func Test_getSubnetUtilisation_EmptyStorage_Fail(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	_, err := getSubnetUtilisation(repo, events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, err.StatusCode(), 404)
+}
@@ -0,0 +1,118 @@
+package gettrends
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// number of latest snapshots evaluated if none is requested
+const (
+	defaultLast = 24
+	maxLast     = 1000
+)
+
+// return lambda handler serving active MTAs of every host over time
+func NewHandler(svc service.Service, snapshots repository.SnapshotRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		trends, svcErr := getTrends(svc, snapshots, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		return service.SuccessResponse(trends), nil
+	}
+}
+
+func getTrends(svc service.Service, snapshots repository.SnapshotRepository, req events.APIGatewayV2HTTPRequest) (models.TrendResponse, errorlib.Error) {
+	// get rule from request or configuration
+	rule, svcErr := svc.GetRule(req)
+	if svcErr != nil {
+		return models.TrendResponse{}, svcErr
+	}
+	last, svcErr := getCount(req, constants.LastKey, defaultLast, maxLast)
+	if svcErr != nil {
+		return models.TrendResponse{}, svcErr
+	}
+	consecutive, svcErr := getCount(req, constants.ConsecutiveKey, 0, maxLast)
+	if svcErr != nil {
+		return models.TrendResponse{}, svcErr
+	}
+	hostname, svcErr := getHostname(req)
+	if svcErr != nil {
+		return models.TrendResponse{}, svcErr
+	}
+	page, svcErr := pagination.Parse(req.QueryStringParameters[constants.LimitKey], req.QueryStringParameters[constants.CursorKey])
+	if svcErr != nil {
+		return models.TrendResponse{}, svcErr
+	}
+	// get latest snapshots from storage
+	snapshotList, svcErr := snapshots.List()
+	if svcErr != nil {
+		return models.TrendResponse{}, svcErr
+	}
+	if len(snapshotList) > last {
+		snapshotList = snapshotList[len(snapshotList)-last:]
+	}
+	// aggregate every snapshot as it is loaded, only its per host counts are kept
+	builder := analyzer.NewTrendBuilder(rule)
+	for _, snapshot := range snapshotList {
+		ipConfig, svcErr := snapshots.Load(snapshot.Id)
+		if svcErr != nil {
+			return models.TrendResponse{}, svcErr
+		}
+		builder.Add(snapshot, ipConfig)
+	}
+	hosts := []models.HostTrend{}
+	for _, trend := range builder.Trends() {
+		if hostname != "" {
+			if ok, _ := path.Match(hostname, trend.Hostname); !ok {
+				continue
+			}
+		}
+		if consecutive > 0 && trend.InefficientStreak < consecutive {
+			continue
+		}
+		hosts = append(hosts, trend)
+	}
+	start, end, nextCursor := page.Bounds(len(hosts))
+	return models.TrendResponse{
+		Rule:        rule,
+		Consecutive: consecutive,
+		Snapshots:   snapshotList,
+		Hosts:       hosts[start:end],
+		NextCursor:  nextCursor,
+	}, nil
+}
+
+// get positive number from query parameter
+func getCount(req events.APIGatewayV2HTTPRequest, key string, defaultCount int, maxCount int) (int, errorlib.Error) {
+	value, ok := req.QueryStringParameters[key]
+	if !ok {
+		return defaultCount, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 || count > maxCount {
+		return 0, errorlib.New(errors.New("invalid "+key+" query parameter. Please provide an integer between 1 and "+strconv.Itoa(maxCount)), http.StatusBadRequest)
+	}
+	return count, nil
+}
+
+// get hostname glob pattern from query parameter
+func getHostname(req events.APIGatewayV2HTTPRequest) (string, errorlib.Error) {
+	hostname := req.QueryStringParameters[constants.HostnameKey]
+	if _, err := path.Match(hostname, ""); err != nil {
+		return "", errorlib.New(errors.New("invalid hostname query parameter. Please provide a hostname or a glob pattern"), http.StatusBadRequest)
+	}
+	return hostname, nil
+}
@@ -0,0 +1,117 @@
+package gettrends
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockSnapshots = [][]models.IpConfig{
+	{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+	},
+	{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+	},
+	{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.4", Hostname: "DummyHostname2", Active: true},
+	},
+}
+
+// make snapshot repository holding mock snapshots in memory, one hour apart
+func newMockSnapshots() repository.SnapshotRepository {
+	snapshots := repository.NewSnapshotRepository(storage.NewMemoryStore(), "snapshots/", 0)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, ipConfig := range mockSnapshots {
+		snapshots.Save(takenAt.Add(time.Duration(i)*time.Hour), ipConfig)
+	}
+	return snapshots
+}
+
+func newService() service.Service {
+	return service.Service{Config: config.Config{Threshold: 1, Match: models.MatchAll}}
+}
+
+func Test_getTrends_Success(t *testing.T) {
+	result, err := getTrends(newService(), newMockSnapshots(), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, len(result.Snapshots), 3)
+	assert.Equal(t, len(result.Hosts), 2)
+	assert.Equal(t, result.Hosts[0].Hostname, "DummyHostname1")
+	assert.Equal(t, result.Hosts[0].InefficientStreak, 2)
+	assert.Equal(t, []int{result.Hosts[0].Points[0].ActiveMTAs, result.Hosts[0].Points[1].ActiveMTAs, result.Hosts[0].Points[2].ActiveMTAs}, []int{2, 1, 0})
+	assert.Equal(t, result.Hosts[1].InefficientStreak, 0)
+}
+
+func Test_getTrends_Filter_Success(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    map[string]string
+		hostnames []string
+		snapshots int
+		cursor    string
+	}{
+		{name: "Consecutive", params: map[string]string{"consecutive": "2"}, hostnames: []string{"DummyHostname1"}, snapshots: 3},
+		{name: "Consecutive not reached", params: map[string]string{"consecutive": "3"}, snapshots: 3},
+		{name: "Last", params: map[string]string{"last": "1", "consecutive": "1"}, hostnames: []string{"DummyHostname1"}, snapshots: 1},
+		{name: "Threshold", params: map[string]string{"threshold": "2", "consecutive": "3"}, hostnames: []string{"DummyHostname1", "DummyHostname2"}, snapshots: 3},
+		{name: "Hostname", params: map[string]string{"hostname": "*2"}, hostnames: []string{"DummyHostname2"}, snapshots: 3},
+		{name: "Limit", params: map[string]string{"limit": "1"}, hostnames: []string{"DummyHostname1"}, snapshots: 3, cursor: pagination.EncodeCursor(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := getTrends(newService(), newMockSnapshots(), events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params})
+			assert.Nil(t, err)
+			var hostnames []string
+			for _, host := range result.Hosts {
+				hostnames = append(hostnames, host.Hostname)
+			}
+			assert.Equal(t, hostnames, tt.hostnames)
+			assert.Equal(t, len(result.Snapshots), tt.snapshots)
+			assert.Equal(t, result.NextCursor, tt.cursor)
+		})
+	}
+}
+
+func Test_getTrends_InvalidQuery_Fail(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+	}{
+		{name: "Last", params: map[string]string{"last": "0"}},
+		{name: "Consecutive", params: map[string]string{"consecutive": "Invalid"}},
+		{name: "Hostname", params: map[string]string{"hostname": "["}},
+		{name: "Threshold", params: map[string]string{"threshold": "-1"}},
+		{name: "Limit", params: map[string]string{"limit": "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getTrends(newService(), newMockSnapshots(), events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params})
+			assert.Equal(t, err.StatusCode(), 400)
+		})
+	}
+}
+
+func Test_NewHandler_NoSnapshots_Success(t *testing.T) {
+	handler := NewHandler(newService(), repository.NewSnapshotRepository(storage.NewMemoryStore(), "snapshots/", 0))
+	resp, err := handler(context.Background(), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.Equal(t, resp.Body, `{"rule":{"maxActive":1,"match":"and"},"snapshots":[],"hosts":[]}`)
+}
@@ -0,0 +1,145 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// return lambda handler serving create, read, update and delete of host metadata and the filtered list of hosts
+func NewHandler(repo repository.HostRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		hostname := req.PathParameters[constants.HostnamePathKey]
+		method := req.RequestContext.HTTP.Method
+		if hostname == "" {
+			if method != http.MethodGet {
+				return service.ErrorResponse(errorlib.New(errors.New("method not allowed"), http.StatusMethodNotAllowed)), nil
+			}
+			hostList, svcErr := listHosts(repo, req)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(hostList), nil
+		}
+		switch method {
+		case http.MethodGet:
+			host, svcErr := getHost(repo, hostname)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(host), nil
+		case http.MethodPut:
+			host, created, svcErr := putHost(repo, hostname, req)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			if created {
+				return service.JSONResponse(http.StatusCreated, host), nil
+			}
+			return service.SuccessResponse(host), nil
+		case http.MethodDelete:
+			if svcErr := deleteHost(repo, hostname); svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusNoContent}, nil
+		default:
+			return service.ErrorResponse(errorlib.New(errors.New("method not allowed"), http.StatusMethodNotAllowed)), nil
+		}
+	}
+}
+
+// get hosts matching the query parameters sorted by hostname, one page at a time
+func listHosts(repo repository.HostRepository, req events.APIGatewayV2HTTPRequest) (models.HostList, errorlib.Error) {
+	f := filter.ParseHost(req.QueryStringParameters)
+	page, svcErr := pagination.Parse(req.QueryStringParameters[constants.LimitKey], req.QueryStringParameters[constants.CursorKey])
+	if svcErr != nil {
+		return models.HostList{}, svcErr
+	}
+	hosts, svcErr := repo.Load()
+	if svcErr != nil {
+		return models.HostList{}, svcErr
+	}
+	matches := []models.Host{}
+	for _, host := range hosts {
+		if f.Match(host) {
+			matches = append(matches, host)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Hostname < matches[j].Hostname
+	})
+	start, end, nextCursor := page.Bounds(len(matches))
+	return models.HostList{
+		Hosts:      matches[start:end],
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func getHost(repo repository.HostRepository, hostname string) (models.Host, errorlib.Error) {
+	hosts, svcErr := repo.Load()
+	if svcErr != nil {
+		return models.Host{}, svcErr
+	}
+	for _, host := range hosts {
+		if host.Hostname == hostname {
+			return host, nil
+		}
+	}
+	return models.Host{}, notFoundError(hostname)
+}
+
+// create or replace the metadata of the host, returns true if the host was created
+func putHost(repo repository.HostRepository, hostname string, req events.APIGatewayV2HTTPRequest) (models.Host, bool, errorlib.Error) {
+	body, svcErr := service.GetBody(req)
+	if svcErr != nil {
+		return models.Host{}, false, svcErr
+	}
+	host, details := validator.DecodeHostForHostname(body, hostname)
+	if len(details) > 0 {
+		return models.Host{}, false, errorlib.NewWithDetails(errors.New("invalid host data"), http.StatusBadRequest, details)
+	}
+	var created bool
+	svcErr = repo.Update(func(hosts []models.Host) ([]models.Host, error) {
+		// recomputed on every attempt as the existing data may have changed
+		created = true
+		for i := range hosts {
+			if hosts[i].Hostname == hostname {
+				hosts[i] = host
+				created = false
+				return hosts, nil
+			}
+		}
+		return append(hosts, host), nil
+	})
+	if svcErr != nil {
+		return models.Host{}, false, svcErr
+	}
+	return host, created, nil
+}
+
+// remove the metadata of the host
+func deleteHost(repo repository.HostRepository, hostname string) errorlib.Error {
+	return repo.Update(func(hosts []models.Host) ([]models.Host, error) {
+		for i := range hosts {
+			if hosts[i].Hostname == hostname {
+				return append(hosts[:i], hosts[i+1:]...), nil
+			}
+		}
+		return nil, notFoundError(hostname)
+	})
+}
+
+func notFoundError(hostname string) errorlib.Error {
+	return errorlib.New(errors.New("host not found for "+hostname), http.StatusNotFound)
+}
@@ -0,0 +1,130 @@
+package hosts
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockHosts = []models.Host{
+	{Hostname: "mta-prod-2", Datacenter: "dc1", Pool: "transactional"},
+	{Hostname: "mta-prod-1", Datacenter: "dc1", Pool: "marketing", Capacity: 20, Tags: []string{"warm-up"}},
+	{Hostname: "mta-prod-3", Datacenter: "dc2", Pool: "marketing"},
+}
+
+// make repository holding mock hosts in memory
+func newMockRepository() repository.HostRepository {
+	repo := repository.NewHostRepository(storage.NewMemoryStore(), "hosts.json")
+	repo.Update(func([]models.Host) ([]models.Host, error) {
+		return append([]models.Host(nil), mockHosts...), nil
+	})
+	return repo
+}
+
+func newRequest(method string, hostname string, body string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{Body: body}
+	req.RequestContext.HTTP.Method = method
+	if hostname != "" {
+		req.PathParameters = map[string]string{"hostname": hostname}
+	}
+	return req
+}
+
+func Test_listHosts_Success(t *testing.T) {
+	req := newRequest(http.MethodGet, "", "")
+	req.QueryStringParameters = map[string]string{"datacenter": "dc1", "limit": "1"}
+	result, err := listHosts(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.HostList{Hosts: []models.Host{mockHosts[1]}, NextCursor: pagination.EncodeCursor(1)})
+
+	req.QueryStringParameters = map[string]string{"pool": "marketing", "tag": "warm-up"}
+	result, err = listHosts(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.HostList{Hosts: []models.Host{mockHosts[1]}})
+}
+
+func Test_getHost_NotFound_Fail(t *testing.T) {
+	_, err := getHost(newMockRepository(), "mta-prod-4")
+	assert.Equal(t, err.Error(), "host not found for mta-prod-4")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_putHost_Success(t *testing.T) {
+	repo := newMockRepository()
+	result, created, err := putHost(repo, "mta-prod-2", newRequest(http.MethodPut, "mta-prod-2", `{"datacenter":"dc2","capacity":5}`))
+	assert.Nil(t, err)
+	assert.False(t, created)
+	assert.Equal(t, result, models.Host{Hostname: "mta-prod-2", Datacenter: "dc2", Capacity: 5})
+
+	result, created, err = putHost(repo, "mta-prod-4", newRequest(http.MethodPut, "mta-prod-4", `{"hostname":"mta-prod-4","pool":"marketing"}`))
+	assert.Nil(t, err)
+	assert.True(t, created)
+	host, _ := getHost(repo, "mta-prod-4")
+	assert.Equal(t, host, result)
+}
+
+func Test_putHost_Fail(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		body     string
+	}{
+		{name: "Empty body", hostname: "mta-prod-4", body: ""},
+		{name: "Unknown field", hostname: "mta-prod-4", body: `{"region":"eu"}`},
+		{name: "Mismatched hostname", hostname: "mta-prod-4", body: `{"hostname":"mta-prod-5"}`},
+		{name: "Invalid hostname", hostname: "mta_prod_4", body: `{}`},
+		{name: "Negative capacity", hostname: "mta-prod-4", body: `{"capacity":-1}`},
+		{name: "Empty tag", hostname: "mta-prod-4", body: `{"tags":[""]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := putHost(newMockRepository(), tt.hostname, newRequest(http.MethodPut, tt.hostname, tt.body))
+			assert.Equal(t, err.StatusCode(), 400)
+		})
+	}
+}
+
+func Test_deleteHost_Success(t *testing.T) {
+	repo := newMockRepository()
+	err := deleteHost(repo, "mta-prod-1")
+	assert.Nil(t, err)
+	hosts, _ := repo.Load()
+	assert.Equal(t, hosts, []models.Host{mockHosts[0], mockHosts[2]})
+
+	err = deleteHost(repo, "mta-prod-1")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_NewHandler(t *testing.T) {
+	handler := NewHandler(newMockRepository())
+	invalidLimit := newRequest(http.MethodGet, "", "")
+	invalidLimit.QueryStringParameters = map[string]string{"limit": "0"}
+	tests := []struct {
+		name       string
+		req        events.APIGatewayV2HTTPRequest
+		statusCode int
+	}{
+		{name: "List", req: newRequest(http.MethodGet, "", ""), statusCode: http.StatusOK},
+		{name: "List with invalid limit", req: invalidLimit, statusCode: http.StatusBadRequest},
+		{name: "Get", req: newRequest(http.MethodGet, "mta-prod-1", ""), statusCode: http.StatusOK},
+		{name: "Create", req: newRequest(http.MethodPut, "mta-prod-4", `{"pool":"marketing"}`), statusCode: http.StatusCreated},
+		{name: "Replace", req: newRequest(http.MethodPut, "mta-prod-4", `{"pool":"transactional"}`), statusCode: http.StatusOK},
+		{name: "Delete", req: newRequest(http.MethodDelete, "mta-prod-4", ""), statusCode: http.StatusNoContent},
+		{name: "Unsupported method", req: newRequest(http.MethodPatch, "mta-prod-4", ""), statusCode: http.StatusMethodNotAllowed},
+		{name: "Unsupported list method", req: newRequest(http.MethodPost, "", ""), statusCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler(context.Background(), tt.req)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, tt.statusCode)
+		})
+	}
+}
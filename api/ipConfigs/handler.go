@@ -0,0 +1,179 @@
+package ipconfigs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// return lambda handler serving create, read, update and delete of single records and the filtered list of records
+func NewHandler(repo repository.IpConfigRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		// records are keyed by the canonical form of their IP
+		ip := validator.NormalizeIp(req.PathParameters[constants.IpPathKey])
+		method := req.RequestContext.HTTP.Method
+		if ip == "" {
+			if method != http.MethodGet {
+				return service.ErrorResponse(errorlib.New(errors.New("method not allowed"), http.StatusMethodNotAllowed)), nil
+			}
+			ipConfigList, svcErr := listIpConfig(repo, req)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(ipConfigList), nil
+		}
+		switch method {
+		case http.MethodGet:
+			ipConfig, svcErr := getIpConfig(repo, ip)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(ipConfig), nil
+		case http.MethodPut:
+			ipConfig, created, svcErr := putIpConfig(repo, ip, req)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			if created {
+				return service.JSONResponse(http.StatusCreated, ipConfig), nil
+			}
+			return service.SuccessResponse(ipConfig), nil
+		case http.MethodPatch:
+			ipConfig, svcErr := patchIpConfig(repo, ip, req)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(ipConfig), nil
+		case http.MethodDelete:
+			if svcErr := deleteIpConfig(repo, ip); svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusNoContent}, nil
+		default:
+			return service.ErrorResponse(errorlib.New(errors.New("method not allowed"), http.StatusMethodNotAllowed)), nil
+		}
+	}
+}
+
+// get records matching the query parameters, one page at a time
+func listIpConfig(repo repository.IpConfigRepository, req events.APIGatewayV2HTTPRequest) (models.IpConfigList, errorlib.Error) {
+	f, svcErr := filter.Parse(req.QueryStringParameters)
+	if svcErr != nil {
+		return models.IpConfigList{}, svcErr
+	}
+	page, svcErr := pagination.Parse(req.QueryStringParameters[constants.LimitKey], req.QueryStringParameters[constants.CursorKey])
+	if svcErr != nil {
+		return models.IpConfigList{}, svcErr
+	}
+	ipConfig, svcErr := repo.Load()
+	if svcErr != nil {
+		return models.IpConfigList{}, svcErr
+	}
+	matches := f.Apply(ipConfig)
+	start, end, nextCursor := page.Bounds(len(matches))
+	return models.IpConfigList{
+		IpConfigs:  matches[start:end],
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func getIpConfig(repo repository.IpConfigRepository, ip string) (models.IpConfig, errorlib.Error) {
+	ipConfig, svcErr := repo.Load()
+	if svcErr != nil {
+		return models.IpConfig{}, svcErr
+	}
+	// the last record wins if the IP is duplicated
+	for i := len(ipConfig) - 1; i >= 0; i-- {
+		if validator.NormalizeIp(ipConfig[i].Ip) == ip {
+			return ipConfig[i], nil
+		}
+	}
+	return models.IpConfig{}, notFoundError(ip)
+}
+
+// create or replace the record of the IP, returns true if the record was created
+func putIpConfig(repo repository.IpConfigRepository, ip string, req events.APIGatewayV2HTTPRequest) (models.IpConfig, bool, errorlib.Error) {
+	body, svcErr := service.GetBody(req)
+	if svcErr != nil {
+		return models.IpConfig{}, false, svcErr
+	}
+	if err := validator.ValidateIp(ip); err != nil {
+		return models.IpConfig{}, false, errorlib.New(err, http.StatusBadRequest)
+	}
+	record, details := validator.DecodeIpConfigForIp(body, ip)
+	if len(details) > 0 {
+		return models.IpConfig{}, false, errorlib.NewWithDetails(errors.New("invalid server data"), http.StatusBadRequest, details)
+	}
+	result, svcErr := repo.Upsert(record)
+	if svcErr != nil {
+		return models.IpConfig{}, false, svcErr
+	}
+	return record, result.Results[0].Status == models.StatusCreated, nil
+}
+
+// change the fields of the IP's record which are set in the request body
+func patchIpConfig(repo repository.IpConfigRepository, ip string, req events.APIGatewayV2HTTPRequest) (models.IpConfig, errorlib.Error) {
+	body, svcErr := service.GetBody(req)
+	if svcErr != nil {
+		return models.IpConfig{}, svcErr
+	}
+	patch, details := validator.DecodeIpConfigPatch(body)
+	if len(details) > 0 {
+		return models.IpConfig{}, errorlib.NewWithDetails(errors.New("invalid server data"), http.StatusBadRequest, details)
+	}
+	var patched models.IpConfig
+	svcErr = repo.Update(func(ipConfig []models.IpConfig) ([]models.IpConfig, error) {
+		found := false
+		for i := range ipConfig {
+			if validator.NormalizeIp(ipConfig[i].Ip) != ip {
+				continue
+			}
+			if patch.Hostname != nil {
+				ipConfig[i].Hostname = *patch.Hostname
+			}
+			if patch.Active != nil {
+				ipConfig[i].Active = *patch.Active
+			}
+			patched = ipConfig[i]
+			found = true
+		}
+		if !found {
+			return nil, notFoundError(ip)
+		}
+		return ipConfig, nil
+	})
+	if svcErr != nil {
+		return models.IpConfig{}, svcErr
+	}
+	return patched, nil
+}
+
+// remove every record of the IP
+func deleteIpConfig(repo repository.IpConfigRepository, ip string) errorlib.Error {
+	return repo.Update(func(ipConfig []models.IpConfig) ([]models.IpConfig, error) {
+		remaining := make([]models.IpConfig, 0, len(ipConfig))
+		for _, record := range ipConfig {
+			if validator.NormalizeIp(record.Ip) != ip {
+				remaining = append(remaining, record)
+			}
+		}
+		if len(remaining) == len(ipConfig) {
+			return nil, notFoundError(ip)
+		}
+		return remaining, nil
+	})
+}
+
+func notFoundError(ip string) errorlib.Error {
+	return errorlib.New(errors.New("ip configuration not found for "+ip), http.StatusNotFound)
+}
@@ -0,0 +1,172 @@
+package ipconfigs
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockIpConfig = []models.IpConfig{
+	{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+	{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+	{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+}
+
+// make repository holding mock data in memory
+func newMockRepository() repository.IpConfigRepository {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save(mockIpConfig)
+	return repo
+}
+
+func newRequest(method string, ip string, body string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{Body: body}
+	req.RequestContext.HTTP.Method = method
+	if ip != "" {
+		req.PathParameters = map[string]string{"ip": ip}
+	}
+	return req
+}
+
+func Test_listIpConfig_Success(t *testing.T) {
+	req := newRequest(http.MethodGet, "", "")
+	req.QueryStringParameters = map[string]string{"hostname": "DummyHostname1", "limit": "1"}
+	result, err := listIpConfig(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.IpConfigList{
+		IpConfigs:  mockIpConfig[:1],
+		NextCursor: pagination.EncodeCursor(1),
+	})
+}
+
+func Test_listIpConfig_InvalidFilter_Fail(t *testing.T) {
+	req := newRequest(http.MethodGet, "", "")
+	req.QueryStringParameters = map[string]string{"active": "dummy"}
+	_, err := listIpConfig(newMockRepository(), req)
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_getIpConfig_Success(t *testing.T) {
+	result, err := getIpConfig(newMockRepository(), "10.0.0.2")
+	assert.Nil(t, err)
+	assert.Equal(t, result, mockIpConfig[1])
+}
+
+func Test_getIpConfig_NotFound_Fail(t *testing.T) {
+	_, err := getIpConfig(newMockRepository(), "10.0.0.4")
+	assert.Equal(t, err.Error(), "ip configuration not found for 10.0.0.4")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_putIpConfig_Success(t *testing.T) {
+	repo := newMockRepository()
+	result, created, err := putIpConfig(repo, "10.0.0.2", newRequest(http.MethodPut, "10.0.0.2", `{"hostname":"DummyHostname3","active":true}`))
+	assert.Nil(t, err)
+	assert.False(t, created)
+	assert.Equal(t, result, models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname3", Active: true})
+
+	result, created, err = putIpConfig(repo, "10.0.0.4", newRequest(http.MethodPut, "10.0.0.4", `{"ip":"10.0.0.4","hostname":"DummyHostname3","active":false}`))
+	assert.Nil(t, err)
+	assert.True(t, created)
+	assert.Equal(t, result, models.IpConfig{Ip: "10.0.0.4", Hostname: "DummyHostname3", Active: false})
+
+	ipConfig, _ := repo.Load()
+	assert.Equal(t, len(ipConfig), 4)
+}
+
+func Test_putIpConfig_Fail(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Empty body", body: ""},
+		{name: "Invalid body", body: "Invalid Data"},
+		{name: "Mismatched ip", body: `{"ip":"10.0.0.5","hostname":"DummyHostname3"}`},
+		{name: "Missing hostname", body: `{"active":true}`},
+		{name: "Missing active", body: `{"hostname":"DummyHostname3"}`},
+		{name: "Unknown field", body: `{"hostname":"DummyHostname3","active":true,"port":25}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := putIpConfig(newMockRepository(), "10.0.0.4", newRequest(http.MethodPut, "10.0.0.4", tt.body))
+			assert.Equal(t, err.StatusCode(), 400)
+		})
+	}
+}
+
+func Test_patchIpConfig_Success(t *testing.T) {
+	repo := newMockRepository()
+	result, err := patchIpConfig(repo, "10.0.0.1", newRequest(http.MethodPatch, "10.0.0.1", `{"active":false}`))
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false})
+	ipConfig, _ := repo.Load()
+	assert.Equal(t, ipConfig[0], result)
+}
+
+func Test_patchIpConfig_NotFound_Fail(t *testing.T) {
+	_, err := patchIpConfig(newMockRepository(), "10.0.0.4", newRequest(http.MethodPatch, "10.0.0.4", `{"active":false}`))
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_deleteIpConfig_Success(t *testing.T) {
+	repo := newMockRepository()
+	err := deleteIpConfig(repo, "10.0.0.1")
+	assert.Nil(t, err)
+	ipConfig, _ := repo.Load()
+	assert.Equal(t, ipConfig, mockIpConfig[1:])
+
+	err = deleteIpConfig(repo, "10.0.0.1")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_NewHandler_CanonicalIp_Success(t *testing.T) {
+	repo := newMockRepository()
+	handler := NewHandler(repo)
+	resp, _ := handler(context.TODO(), newRequest(http.MethodPut, "2001:DB8::1", `{"hostname":"DummyHostname3","active":true}`))
+	assert.Equal(t, resp.StatusCode, http.StatusCreated)
+	resp, _ = handler(context.TODO(), newRequest(http.MethodPut, "2001:db8:0::1", `{"ip":"2001:Db8::1","hostname":"DummyHostname4","active":true}`))
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	resp, _ = handler(context.TODO(), newRequest(http.MethodPatch, "::ffff:10.0.0.1", `{"active":false}`))
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	ipConfig, _ := repo.Load()
+	assert.Equal(t, ipConfig, []models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false},
+		mockIpConfig[1],
+		mockIpConfig[2],
+		{Ip: "2001:db8::1", Hostname: "DummyHostname4", Active: true},
+	})
+	resp, _ = handler(context.TODO(), newRequest(http.MethodDelete, "2001:0DB8::0001", ""))
+	assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+}
+
+func Test_NewHandler_Success(t *testing.T) {
+	handler := NewHandler(newMockRepository())
+	tests := []struct {
+		name       string
+		req        events.APIGatewayV2HTTPRequest
+		statusCode int
+	}{
+		{name: "List", req: newRequest(http.MethodGet, "", ""), statusCode: http.StatusOK},
+		{name: "Get", req: newRequest(http.MethodGet, "10.0.0.1", ""), statusCode: http.StatusOK},
+		{name: "Create", req: newRequest(http.MethodPut, "10.0.0.4", `{"hostname":"DummyHostname3","active":true}`), statusCode: http.StatusCreated},
+		{name: "Replace", req: newRequest(http.MethodPut, "10.0.0.4", `{"hostname":"DummyHostname3","active":false}`), statusCode: http.StatusOK},
+		{name: "Patch", req: newRequest(http.MethodPatch, "10.0.0.4", `{"hostname":"DummyHostname4"}`), statusCode: http.StatusOK},
+		{name: "Delete", req: newRequest(http.MethodDelete, "10.0.0.4", ""), statusCode: http.StatusNoContent},
+		{name: "Unsupported method", req: newRequest(http.MethodPost, "10.0.0.4", ""), statusCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler(context.Background(), tt.req)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, tt.statusCode)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package addmockdata
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// return lambda handler adding server data
+func NewHandler(repo repository.IpConfigRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		resp, svcErr := addIpConfig(repo, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+
+		return service.MockSuccessResponse(resp), nil
+	}
+}
+
+// create or update all records of the request body, using IP as key
+func addIpConfig(repo repository.IpConfigRepository, req events.APIGatewayV2HTTPRequest) (models.IngestResponse, errorlib.Error) {
+	//return error if body is empty
+	body, svcErr := service.GetBody(req)
+	if svcErr != nil {
+		return models.IngestResponse{}, svcErr
+	}
+	//convert request body to go structs
+	rawRecords, svcErr := splitRecords(req, body)
+	if svcErr != nil {
+		log.Printf("%v", svcErr)
+		return models.IngestResponse{}, svcErr
+	}
+	if len(rawRecords) == 0 {
+		return models.IngestResponse{}, errorlib.New(errors.New("request body cannot be empty. Please provide valid data"), http.StatusBadRequest)
+	}
+	records, details := validator.DecodeIpConfigs(rawRecords)
+	// the batch is only stored if every record is valid
+	if len(details) > 0 {
+		return models.IngestResponse{}, errorlib.NewWithDetails(errors.New("invalid server data"), http.StatusBadRequest, details)
+	}
+	// merge server data into existing data in storage with a single write, a new file is created if none exists
+	result, svcErr := repo.Upsert(records...)
+	if svcErr != nil {
+		return models.IngestResponse{}, svcErr
+	}
+	resp := models.IngestResponse{
+		Records:    len(result.Results),
+		Results:    result.Results,
+		Duplicates: result.Duplicates,
+	}
+	for _, recordResult := range result.Results {
+		if recordResult.Status == models.StatusCreated {
+			resp.Created++
+		} else {
+			resp.Updated++
+		}
+	}
+	return resp, nil
+}
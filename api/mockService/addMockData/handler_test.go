@@ -0,0 +1,420 @@
+package addmockdata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	dummyS3 "github.com/mta-hosting-optimizer/lib/aws/s3/dummy"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	s3helper "github.com/mta-hosting-optimizer/lib/s3Helper"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockServerJsonData = `[{
+	"ip":"10.0.0.1",
+	"hostname":"DummyHostname1",
+	"active": true
+},{
+	"ip":"10.0.0.2",
+	"hostname":"DummyHostname2",
+	"active": false
+}]`
+
+// make repository reading server data through the dummy S3 service
+func newS3Repository(svc service.Service) repository.IpConfigRepository {
+	return repository.NewIpConfigRepository(storage.NewS3Store(svc, svc.Config.Bucket), svc.Config.Key)
+}
+
+func Test_addIpConfig_EmptyBody_Fail(t *testing.T) {
+	svc := service.Service{}
+	req := events.APIGatewayV2HTTPRequest{}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.Error(), "request body cannot be empty. Please provide valid data")
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_addIpConfig_InvalidRequest_Fail(t *testing.T) {
+	svc := service.Service{}
+	req := events.APIGatewayV2HTTPRequest{Body: "Invalid Data"}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.StatusCode(), 400)
+
+}
+func Test_addIpConfig_GetMockData_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{}, errors.New("get object failed")
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `{
+		"ip":"10.0.0.1",
+		"hostname":"DummyHostname1",
+		"active": true
+	}`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.Error(), "get object failed")
+	assert.Equal(t, err.StatusCode(), 500)
+
+}
+func Test_addIpConfig_InvalidMockData_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString("InvalidData")),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `{
+		"ip":"10.0.0.1",
+		"hostname":"DummyHostname1",
+		"active": true
+	}`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.StatusCode(), 500)
+
+}
+func Test_addIPConfig_PutObject_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error) {
+				return &s3.PutObjectOutput{}, errors.New("put s3 object failed")
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `{
+		"ip":"10.0.0.1",
+		"hostname":"DummyHostname1",
+		"active": true
+	}`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.Error(), "put s3 object failed")
+	assert.Equal(t, err.StatusCode(), 500)
+
+}
+
+func Test_addIPConfig_EmptyS3Bucket_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+			DummyPutObjectWithContext: func(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error) {
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `{
+		"ip":"10.0.0.1",
+		"hostname":"DummyHostname1",
+		"active": true
+	}`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Nil(t, err)
+
+}
+
+func Test_addIPConfig_ExistingDataInS3Bucket_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error) {
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `{
+		"ip":"10.0.0.1",
+		"hostname":"DummyHostname1",
+		"active": true
+	}`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Nil(t, err)
+
+}
+
+func Test_addIpConfig_Batch_Success(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		params  map[string]string
+		body    string
+	}{
+		{
+			name: "JSON array",
+			body: `[
+				{"ip":"10.0.0.3","hostname":"DummyHostname3","active":true},
+				{"ip":"10.0.0.4","hostname":"DummyHostname3","active":false}
+			]`,
+		},
+		{
+			name:    "NDJSON",
+			headers: map[string]string{"content-type": "application/x-ndjson"},
+			body:    "{\"ip\":\"10.0.0.3\",\"hostname\":\"DummyHostname3\",\"active\":true}\n\n{\"ip\":\"10.0.0.4\",\"hostname\":\"DummyHostname3\",\"active\":false}\n",
+		},
+		{
+			name:    "CSV",
+			headers: map[string]string{"content-type": "text/csv; charset=utf-8"},
+			body:    "IP,Hostname,Active,Owner\n10.0.0.3,DummyHostname3,true,ops\n10.0.0.4,DummyHostname3,FALSE,ops\n",
+		},
+		{
+			name:   "CSV with column mapping",
+			params: map[string]string{"format": "csv", "columns": "ip=Address,hostname=Server,active=Up"},
+			body:   "Server,Address,Up\nDummyHostname3,10.0.0.3,1\nDummyHostname3,10.0.0.4,0\n",
+		},
+		{
+			name: "NDJSON without content type",
+			body: "{\"ip\":\"10.0.0.3\",\"hostname\":\"DummyHostname3\",\"active\":true}\n{\"ip\":\"10.0.0.4\",\"hostname\":\"DummyHostname3\",\"active\":false}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			putCalls := 0
+			var putBody []byte
+			sess, _ := session.NewSession()
+			svc := service.Service{
+				S3: dummyS3.S3Interface{
+					DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+						return &s3.HeadObjectOutput{}, nil
+					},
+					DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+						return &s3.GetObjectOutput{
+							Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+						}, nil
+					},
+					DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+						putCalls++
+						putBody, _ = io.ReadAll(input.Body)
+						return &s3.PutObjectOutput{}, nil
+					},
+				},
+				Sess: sess,
+			}
+			req := events.APIGatewayV2HTTPRequest{Headers: tt.headers, QueryStringParameters: tt.params, Body: tt.body}
+			resp, err := addIpConfig(newS3Repository(svc), req)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.Records, 2)
+			assert.Equal(t, resp.Created, 2)
+			assert.Equal(t, putCalls, 1)
+			assert.JSONEq(t, string(putBody), `[
+				{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true},
+				{"ip":"10.0.0.2","hostname":"DummyHostname2","active":false},
+				{"ip":"10.0.0.3","hostname":"DummyHostname3","active":true},
+				{"ip":"10.0.0.4","hostname":"DummyHostname3","active":false}
+			]`)
+		})
+	}
+}
+
+func Test_addIpConfig_InvalidRecords_Fail(t *testing.T) {
+	svc := service.Service{}
+	req := events.APIGatewayV2HTTPRequest{
+		Headers: map[string]string{"content-type": "application/x-ndjson"},
+		Body: `{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true}
+{"ip":"","hostname":"DummyHostname1","active":true}
+{"ip":"10.0.0.3","hostname":"DummyHostname1","active":"yes"}
+{"ip":"10.0.0.4","hostname":""`,
+	}
+	resp, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, resp, models.IngestResponse{})
+	assert.Equal(t, err.Error(), "invalid server data")
+	assert.Equal(t, err.StatusCode(), 400)
+	assert.Equal(t, len(err.Details()), 3)
+	assert.Equal(t, err.Details()[0], errorlib.Detail{Field: "[1].ip", Message: "ip is required"})
+	assert.Equal(t, err.Details()[1], errorlib.Detail{Field: "[2].active", Message: "active must be true or false"})
+	assert.Equal(t, err.Details()[2].Field, "[3]")
+}
+
+func Test_addIpConfig_InvalidFields_Fail(t *testing.T) {
+	svc := service.Service{}
+	req := events.APIGatewayV2HTTPRequest{Body: `[
+		{"ip":"10.0.0.256","hostname":"-DummyHostname1","active":true,"port":25},
+		{"ip":"2001:db8::1","hostname":"DummyHostname1"}
+	]`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.StatusCode(), 400)
+	assert.Equal(t, err.Details(), []errorlib.Detail{
+		{Field: "[0].port", Message: "unknown field"},
+		{Field: "[0].ip", Message: "ip must be a valid IPv4 or IPv6 address"},
+		{Field: "[0].hostname", Message: "hostname must be a valid RFC 1123 hostname"},
+		{Field: "[1].active", Message: "active is required"},
+	})
+}
+
+func Test_addIpConfig_EmptyArray_Fail(t *testing.T) {
+	svc := service.Service{}
+	req := events.APIGatewayV2HTTPRequest{Body: "[]"}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_addIpConfig_ConcurrentWriteConflict_Fail(t *testing.T) {
+	s3helper.RetryDelay = 0
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+					ETag: aws.String(`"etag1"`),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error) {
+				return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `{
+		"ip":"10.0.0.1",
+		"hostname":"DummyHostname1",
+		"active": true
+	}`}
+	_, err := addIpConfig(newS3Repository(svc), req)
+	assert.Equal(t, err.Error(), "data was modified concurrently. Please retry the request")
+	assert.Equal(t, err.StatusCode(), 409)
+}
+
+func Test_addIpConfig_Upsert_Success(t *testing.T) {
+	var putBody []byte
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(`[
+						{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true},
+						{"ip":"10.0.0.2","hostname":"DummyHostname2","active":false},
+						{"ip":"10.0.0.1","hostname":"DummyHostname3","active":false}
+					]`)),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				putBody, _ = io.ReadAll(input.Body)
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	req := events.APIGatewayV2HTTPRequest{Body: `[
+		{"ip":"10.0.0.2","hostname":"DummyHostname4","active":true},
+		{"ip":"10.0.0.5","hostname":"DummyHostname4","active":true}
+	]`}
+	resp, err := addIpConfig(newS3Repository(svc), req)
+	assert.Nil(t, err)
+	assert.Equal(t, resp, models.IngestResponse{
+		Records: 2,
+		Created: 1,
+		Updated: 1,
+		Results: []models.RecordResult{
+			{Ip: "10.0.0.2", Status: "updated"},
+			{Ip: "10.0.0.5", Status: "created"},
+		},
+		Duplicates: []string{"10.0.0.1"},
+	})
+	assert.JSONEq(t, string(putBody), `[
+		{"ip":"10.0.0.1","hostname":"DummyHostname3","active":false},
+		{"ip":"10.0.0.2","hostname":"DummyHostname4","active":true},
+		{"ip":"10.0.0.5","hostname":"DummyHostname4","active":true}
+	]`)
+}
+
+func Test_addIpConfig_InvalidCSV_Fail(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		body   string
+		detail string
+	}{
+		{name: "Unsupported format", params: map[string]string{"format": "xml"}, body: "ip\n"},
+		{name: "Unknown column field", params: map[string]string{"format": "csv", "columns": "port=Port"}, body: "ip\n"},
+		{name: "Mapped column missing", params: map[string]string{"format": "csv", "columns": "ip=Address"}, body: "ip,hostname,active\n10.0.0.3,DummyHostname3,true\n"},
+		{name: "Invalid active", params: map[string]string{"format": "csv"}, body: "ip,hostname,active\n10.0.0.3,DummyHostname3,yes\n", detail: "[0].active"},
+		{name: "Missing hostname", params: map[string]string{"format": "csv"}, body: "ip,hostname,active\n10.0.0.3,,true\n", detail: "[0].hostname"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params, Body: tt.body}
+			_, err := addIpConfig(newS3Repository(service.Service{}), req)
+			assert.Equal(t, err.StatusCode(), 400)
+			if tt.detail != "" {
+				assert.Equal(t, err.Details()[0].Field, tt.detail)
+			}
+		})
+	}
+}
+
+func Test_addIpConfig_Base64Latin1CSV_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	// spreadsheet export in ISO-8859-1, header "Hôte" is not valid UTF-8
+	body := []byte("ip,H\xf4te,active\n10.0.0.1,DummyHostname1,true\n")
+	req := events.APIGatewayV2HTTPRequest{
+		QueryStringParameters: map[string]string{"columns": "hostname=Hôte"},
+		Headers:               map[string]string{"content-type": "text/csv; charset=iso-8859-1"},
+		Body:                  base64.StdEncoding.EncodeToString(body),
+		IsBase64Encoded:       true,
+	}
+	resp, err := addIpConfig(repo, req)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.Created, 1)
+	ipConfig, _ := repo.Load()
+	assert.Equal(t, ipConfig, []models.IpConfig{{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true}})
+
+	req.Body = "Invalid Data"
+	_, err = addIpConfig(repo, req)
+	assert.Equal(t, err.Error(), "malformed request body: invalid base64 encoding")
+	assert.Equal(t, err.StatusCode(), 400)
+}
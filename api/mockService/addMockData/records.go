@@ -0,0 +1,43 @@
+package addmockdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/codec"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+)
+
+// split decoded request body into raw records. The format is taken from the format query parameter or else the
+// content type: a JSON record, array of records or stream of records, newline delimited records, or CSV
+// with a header row whose columns may be mapped to fields with the columns query parameter
+func splitRecords(req events.APIGatewayV2HTTPRequest, body []byte) ([]json.RawMessage, errorlib.Error) {
+	format := req.QueryStringParameters[constants.FormatKey]
+	if format == "" {
+		format, _ = codec.FormatOf(req.Headers["content-type"])
+	}
+	var rawRecords []json.RawMessage
+	var err error
+	switch format {
+	case codec.FormatCSV:
+		columns, columnsErr := codec.ParseColumns(req.QueryStringParameters[constants.ColumnsKey])
+		if columnsErr != nil {
+			return nil, errorlib.New(fmt.Errorf("invalid columns query parameter: %v", columnsErr), http.StatusBadRequest)
+		}
+		rawRecords, err = codec.SplitCSV(body, columns)
+	case codec.FormatNDJSON:
+		rawRecords, err = codec.SplitNDJSON(body)
+	case codec.FormatJSON, "":
+		rawRecords, err = codec.SplitJSON(body)
+	default:
+		return nil, errorlib.New(errors.New("invalid format query parameter. Please provide json, csv or ndjson"), http.StatusBadRequest)
+	}
+	if err != nil {
+		return nil, errorlib.New(fmt.Errorf("malformed request body: %v", err), http.StatusBadRequest)
+	}
+	return rawRecords, nil
+}
@@ -0,0 +1,122 @@
+package getmockdata
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/codec"
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// return lambda handler serving server data as JSON, CSV or NDJSON
+func NewHandler(repo repository.IpConfigRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		format, svcErr := service.GetFormat(req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		if format != codec.FormatJSON {
+			body, nextCursor, svcErr := exportIpConfig(repo, req, format)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			resp := service.ContentResponse(http.StatusOK, format, body)
+			if nextCursor != "" {
+				resp.Headers[constants.NextCursorHeader] = nextCursor
+			}
+			return resp, nil
+		}
+		ipConfig, svcErr := getIpConfig(repo, req)
+		if svcErr != nil {
+			return service.ErrorResponse(svcErr), nil
+		}
+		return service.SuccessResponse(ipConfig), nil
+	}
+}
+
+// records of a page of server data matching the query parameters
+type query struct {
+	records    []models.IpConfig
+	fields     []string // fields to return, every field if empty
+	nextCursor string
+	paged      bool // limit or cursor was requested
+}
+
+// get server data matching the query parameters. Without limit and cursor the matching records are
+// returned as a plain list, otherwise one page at a time
+func getIpConfig(repo repository.IpConfigRepository, req events.APIGatewayV2HTTPRequest) (interface{}, errorlib.Error) {
+	q, svcErr := queryIpConfig(repo, req)
+	if svcErr != nil {
+		return nil, svcErr
+	}
+	var records interface{} = q.records
+	if len(q.fields) > 0 {
+		records = filter.Project(q.records, q.fields)
+	}
+	if !q.paged {
+		return records, nil
+	}
+	return models.MockDataPage{IpConfigs: records, NextCursor: q.nextCursor}, nil
+}
+
+// encode server data matching the query parameters as CSV or NDJSON. The cursor of the next page
+// is returned separately
+func exportIpConfig(repo repository.IpConfigRepository, req events.APIGatewayV2HTTPRequest, format string) ([]byte, string, errorlib.Error) {
+	q, svcErr := queryIpConfig(repo, req)
+	if svcErr != nil {
+		return nil, "", svcErr
+	}
+	var buf bytes.Buffer
+	var err error
+	switch {
+	case format == codec.FormatCSV:
+		err = codec.WriteIpConfigCSV(&buf, q.records, q.fields)
+	case len(q.fields) > 0:
+		err = codec.WriteNDJSON(&buf, filter.Project(q.records, q.fields))
+	default:
+		err = codec.WriteNDJSON(&buf, q.records)
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		return nil, "", errorlib.New(err, http.StatusInternalServerError)
+	}
+	return buf.Bytes(), q.nextCursor, nil
+}
+
+func queryIpConfig(repo repository.IpConfigRepository, req events.APIGatewayV2HTTPRequest) (query, errorlib.Error) {
+	params := req.QueryStringParameters
+	f, svcErr := filter.Parse(params)
+	if svcErr != nil {
+		return query{}, svcErr
+	}
+	fields, svcErr := filter.ParseFields(params[constants.FieldsKey])
+	if svcErr != nil {
+		return query{}, svcErr
+	}
+	page, svcErr := pagination.Parse(params[constants.LimitKey], params[constants.CursorKey])
+	if svcErr != nil {
+		return query{}, svcErr
+	}
+	// get mock data from storage
+	ipConfig, svcErr := repo.Load()
+	if svcErr != nil {
+		return query{}, svcErr
+	}
+	matches := f.Apply(ipConfig)
+	start, end, nextCursor := page.Bounds(len(matches))
+	return query{
+		records:    matches[start:end],
+		fields:     fields,
+		nextCursor: nextCursor,
+		paged:      params[constants.LimitKey] != "" || params[constants.CursorKey] != "",
+	}, nil
+}
@@ -0,0 +1,220 @@
+package getmockdata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	dummyS3 "github.com/mta-hosting-optimizer/lib/aws/s3/dummy"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/pagination"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockServerJsonData = `[{
+	"ip":"DummyIP1",
+	"hostname":"DummyHostname1",
+	"active": true
+},{
+	"ip":"DummyIP2",
+	"hostname":"DummyHostname2",
+	"active": false
+}]`
+
+// make repository reading server data through the dummy S3 service
+func newS3Repository(svc service.Service) repository.IpConfigRepository {
+	return repository.NewIpConfigRepository(storage.NewS3Store(svc, svc.Config.Bucket), svc.Config.Key)
+}
+
+func Test_getIPConfig_EmptyBucket_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+		},
+		Sess: sess,
+	}
+	result, err := getIpConfig(newS3Repository(svc), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, result)
+	assert.Equal(t, err.Error(), "server information not found")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+func Test_getIPConfig_GetS3Object_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{}, errors.New("get object failed")
+			},
+		},
+		Sess: sess,
+	}
+	result, err := getIpConfig(newS3Repository(svc), events.APIGatewayV2HTTPRequest{})
+	assert.Nil(t, result)
+	assert.Equal(t, err.Error(), "get object failed")
+	assert.Equal(t, err.StatusCode(), 500)
+}
+
+func Test_getIPConfig_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(mockServerJsonData)),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	result, err := getIpConfig(newS3Repository(svc), events.APIGatewayV2HTTPRequest{})
+	assert.Equal(t, result, []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP2", Hostname: "DummyHostname2", Active: false},
+	})
+	assert.Nil(t, err)
+}
+
+var mockIpConfig = []models.IpConfig{
+	{Ip: "10.0.1.1", Hostname: "mta-prod-1", Active: true},
+	{Ip: "10.0.1.2", Hostname: "mta-prod-1", Active: false},
+	{Ip: "10.0.2.1", Hostname: "mta-prod-2", Active: true},
+	{Ip: "2001:db8::1", Hostname: "mta-staging-1", Active: true},
+}
+
+// make repository holding mock data in memory
+func newMockRepository() repository.IpConfigRepository {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	repo.Save(mockIpConfig)
+	return repo
+}
+
+func Test_getIpConfig_Filter_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected []models.IpConfig
+	}{
+		{name: "Hostname", params: map[string]string{"hostname": "mta-prod-2"}, expected: mockIpConfig[2:3]},
+		{name: "Hostname glob", params: map[string]string{"hostname": "mta-prod-*"}, expected: mockIpConfig[:3]},
+		{name: "Active", params: map[string]string{"active": "false"}, expected: mockIpConfig[1:2]},
+		{name: "IP prefix", params: map[string]string{"ip": "10.0.1."}, expected: mockIpConfig[:2]},
+		{name: "CIDR", params: map[string]string{"ip": "10.0.0.0/16"}, expected: mockIpConfig[:3]},
+		{name: "IPv6 CIDR", params: map[string]string{"ip": "2001:db8::/32"}, expected: mockIpConfig[3:]},
+		{name: "No match", params: map[string]string{"hostname": "mta-dev-*"}, expected: []models.IpConfig{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := getIpConfig(newMockRepository(), events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params})
+			assert.Nil(t, err)
+			assert.Equal(t, result, tt.expected)
+		})
+	}
+}
+
+func Test_getIpConfig_Fields_Success(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"hostname": "mta-prod-1", "fields": "ip,active"}}
+	result, err := getIpConfig(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, []map[string]interface{}{
+		{"ip": "10.0.1.1", "active": true},
+		{"ip": "10.0.1.2", "active": false},
+	})
+}
+
+func Test_getIpConfig_Pagination_Success(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"limit": "3"}}
+	result, err := getIpConfig(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.MockDataPage{IpConfigs: mockIpConfig[:3], NextCursor: pagination.EncodeCursor(3)})
+
+	req.QueryStringParameters["cursor"] = pagination.EncodeCursor(3)
+	result, err = getIpConfig(newMockRepository(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.MockDataPage{IpConfigs: mockIpConfig[3:]})
+}
+
+func Test_getIpConfig_InvalidQuery_Fail(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+	}{
+		{name: "Hostname glob", params: map[string]string{"hostname": "mta-[prod"}},
+		{name: "CIDR", params: map[string]string{"ip": "10.0.0.0/33"}},
+		{name: "Fields", params: map[string]string{"fields": "ip,port"}},
+		{name: "Limit", params: map[string]string{"limit": "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getIpConfig(newMockRepository(), events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params})
+			assert.Equal(t, err.StatusCode(), 400)
+		})
+	}
+}
+
+func Test_NewHandler_Export_Success(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      map[string]string
+		headers     map[string]string
+		contentType string
+		body        string
+		nextCursor  string
+	}{
+		{
+			name:        "CSV format parameter",
+			params:      map[string]string{"format": "csv", "hostname": "mta-prod-1"},
+			contentType: "text/csv",
+			body:        "ip,hostname,active\n10.0.1.1,mta-prod-1,true\n10.0.1.2,mta-prod-1,false\n",
+		},
+		{
+			name:        "CSV accept header with fields and pagination",
+			params:      map[string]string{"fields": "hostname,ip", "limit": "1"},
+			headers:     map[string]string{"accept": "text/csv;q=0.9, application/json"},
+			contentType: "text/csv",
+			body:        "hostname,ip\nmta-prod-1,10.0.1.1\n",
+			nextCursor:  pagination.EncodeCursor(1),
+		},
+		{
+			name:        "NDJSON",
+			params:      map[string]string{"format": "ndjson", "fields": "ip", "active": "false"},
+			contentType: "application/x-ndjson",
+			body:        "{\"ip\":\"10.0.1.2\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayV2HTTPRequest{QueryStringParameters: tt.params, Headers: tt.headers}
+			resp, err := NewHandler(newMockRepository())(context.Background(), req)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, 200)
+			assert.Equal(t, resp.Headers["Content-Type"], tt.contentType)
+			assert.Equal(t, resp.Headers["X-Next-Cursor"], tt.nextCursor)
+			assert.Equal(t, resp.Body, tt.body)
+		})
+	}
+}
+
+func Test_NewHandler_InvalidFormat_Fail(t *testing.T) {
+	req := events.APIGatewayV2HTTPRequest{QueryStringParameters: map[string]string{"format": "xml"}}
+	resp, err := NewHandler(newMockRepository())(context.Background(), req)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.StatusCode, 400)
+}
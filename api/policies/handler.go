@@ -0,0 +1,52 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// return lambda handler serving and replacing the threshold policies of host groups
+func NewHandler(repo repository.PolicyRepository) service.Handler {
+	return func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		switch req.RequestContext.HTTP.Method {
+		case http.MethodGet:
+			policies, svcErr := repo.Load()
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(policies), nil
+		case http.MethodPut:
+			policies, svcErr := putPolicies(repo, req)
+			if svcErr != nil {
+				return service.ErrorResponse(svcErr), nil
+			}
+			return service.SuccessResponse(policies), nil
+		default:
+			return service.ErrorResponse(errorlib.New(errors.New("method not allowed"), http.StatusMethodNotAllowed)), nil
+		}
+	}
+}
+
+// replace all policies with the ordered list of the request body
+func putPolicies(repo repository.PolicyRepository, req events.APIGatewayV2HTTPRequest) ([]models.Policy, errorlib.Error) {
+	body, svcErr := service.GetBody(req)
+	if svcErr != nil {
+		return nil, svcErr
+	}
+	policies, details := validator.DecodePolicies(body)
+	if len(details) > 0 {
+		return nil, errorlib.NewWithDetails(errors.New("invalid policies"), http.StatusBadRequest, details)
+	}
+	if svcErr := repo.Save(policies); svcErr != nil {
+		return nil, svcErr
+	}
+	return policies, nil
+}
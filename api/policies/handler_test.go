@@ -0,0 +1,84 @@
+package policies
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequest(method string, body string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{Body: body}
+	req.RequestContext.HTTP.Method = method
+	return req
+}
+
+func Test_putPolicies_Success(t *testing.T) {
+	repo := repository.NewPolicyRepository(storage.NewMemoryStore(), "policies.json")
+	result, err := putPolicies(repo, newRequest(http.MethodPut, `[
+		{"name":"marketing","hostname":"mta-marketing-*","rule":{"maxActiveRatio":0.2,"minTotalIps":10}},
+		{"name":"warm-up","tags":["warm-up"],"rule":{"maxActive":0}}
+	]`))
+	assert.Nil(t, err)
+	ratio, minTotalIps, maxActive := 0.2, 10, 0
+	expected := []models.Policy{
+		{Name: "marketing", Hostname: "mta-marketing-*", Rule: models.Rule{MaxActiveRatio: &ratio, MinTotalIps: &minTotalIps}},
+		{Name: "warm-up", Tags: []string{"warm-up"}, Rule: models.Rule{MaxActive: &maxActive}},
+	}
+	assert.Equal(t, result, expected)
+	policies, _ := repo.Load()
+	assert.Equal(t, policies, expected)
+}
+
+func Test_putPolicies_Fail(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Empty body", body: ""},
+		{name: "Not an array", body: `{"name":"marketing"}`},
+		{name: "Unknown field", body: `[{"name":"marketing","hostname":"mta-*","threshold":1,"rule":{"maxActive":1}}]`},
+		{name: "Missing selector", body: `[{"name":"marketing","rule":{"maxActive":1}}]`},
+		{name: "Missing criteria", body: `[{"name":"marketing","hostname":"mta-*","rule":{}}]`},
+		{name: "Duplicated name", body: `[{"name":"a","hostname":"mta-*","rule":{"maxActive":1}},{"name":"a","hostname":"mx-*","rule":{"maxActive":1}}]`},
+		{name: "Reserved name", body: `[{"name":"default","hostname":"mta-*","rule":{"maxActive":1}}]`},
+		{name: "Invalid pattern", body: `[{"name":"marketing","hostname":"mta-[","rule":{"maxActive":1}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewPolicyRepository(storage.NewMemoryStore(), "policies.json")
+			_, err := putPolicies(repo, newRequest(http.MethodPut, tt.body))
+			assert.Equal(t, err.StatusCode(), 400)
+		})
+	}
+}
+
+func Test_NewHandler(t *testing.T) {
+	handler := NewHandler(repository.NewPolicyRepository(storage.NewMemoryStore(), "policies.json"))
+	tests := []struct {
+		name       string
+		req        events.APIGatewayV2HTTPRequest
+		statusCode int
+		body       string
+	}{
+		{name: "Get without policies", req: newRequest(http.MethodGet, ""), statusCode: http.StatusOK, body: "[]"},
+		{name: "Put", req: newRequest(http.MethodPut, `[{"name":"a","hostname":"mta-*","rule":{"maxActive":1}}]`), statusCode: http.StatusOK},
+		{name: "Get", req: newRequest(http.MethodGet, ""), statusCode: http.StatusOK, body: `[{"name":"a","hostname":"mta-*","rule":{"maxActive":1,"match":""}}]`},
+		{name: "Unsupported method", req: newRequest(http.MethodDelete, ""), statusCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler(context.Background(), tt.req)
+			assert.Nil(t, err)
+			assert.Equal(t, resp.StatusCode, tt.statusCode)
+			if tt.body != "" {
+				assert.Equal(t, resp.Body, tt.body)
+			}
+		})
+	}
+}
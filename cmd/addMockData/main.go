@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	addmockdata "github.com/mta-hosting-optimizer/api/mockService/addMockData"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	store, err := storage.New(svc)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	snapshots := repository.NewSnapshotRepository(store, cfg.SnapshotPrefix, cfg.SnapshotMaxAge())
+	indexRepo := repository.NewIndexRepository(store, cfg.IndexKey)
+	repo := repository.NewIpConfigRepository(store, cfg.Key, repository.WithSnapshots(snapshots), repository.WithIndex(indexRepo), repository.WithShards(cfg.Shards, cfg.ReadConcurrency))
+	lambda.Start(addmockdata.NewHandler(repo))
+}
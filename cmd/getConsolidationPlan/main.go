@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	getconsolidationplan "github.com/mta-hosting-optimizer/api/getConsolidationPlan"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	store, err := storage.New(svc)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	repo := repository.NewIpConfigRepository(store, cfg.Key, repository.WithShards(cfg.Shards, cfg.ReadConcurrency))
+	policyRepo := repository.NewPolicyRepository(store, cfg.PolicyKey)
+	hostRepo := repository.NewHostRepository(store, cfg.HostKey)
+	lambda.Start(getconsolidationplan.NewHandler(svc, repo, policyRepo, hostRepo))
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	getinefficientservers "github.com/mta-hosting-optimizer/api/getInefficientServers"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	store, err := storage.New(svc)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	repo := repository.NewIpConfigRepository(store, cfg.Key, repository.WithShards(cfg.Shards, cfg.ReadConcurrency))
+	policyRepo := repository.NewPolicyRepository(store, cfg.PolicyKey)
+	hostRepo := repository.NewHostRepository(store, cfg.HostKey)
+	indexRepo := repository.NewIndexRepository(store, cfg.IndexKey)
+	lambda.Start(getinefficientservers.NewHandler(svc, repo, policyRepo, hostRepo, indexRepo))
+}
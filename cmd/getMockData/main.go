@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	getmockdata "github.com/mta-hosting-optimizer/api/mockService/getMockData"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	store, err := storage.New(svc)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	repo := repository.NewIpConfigRepository(store, cfg.Key, repository.WithShards(cfg.Shards, cfg.ReadConcurrency))
+	lambda.Start(getmockdata.NewHandler(repo))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	getsnapshotdiff "github.com/mta-hosting-optimizer/api/getSnapshotDiff"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	store, err := storage.New(svc)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	snapshots := repository.NewSnapshotRepository(store, cfg.SnapshotPrefix, cfg.SnapshotMaxAge())
+	lambda.Start(getsnapshotdiff.NewHandler(svc, snapshots))
+}
@@ -0,0 +1,157 @@
+// mta-optimizer finds inefficient servers in a local export of server data, imports records into
+// or exports records from the configured storage, rebuilds the index of the stored records and
+// takes snapshots of them.
+//
+//	mta-optimizer [flags] [file]
+//	mta-optimizer import [flags] [file]
+//	mta-optimizer export [flags]
+//	mta-optimizer rebuild-index
+//	mta-optimizer snapshot
+//
+// Records are read from file, or from stdin if no file or - is given, as JSON, NDJSON or CSV with a header row.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	"github.com/mta-hosting-optimizer/lib/codec"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// output format of the analysis besides the codec formats
+const formatTable = "table"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "mta-optimizer: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "import":
+			return runImport(args[1:], stdin, stdout)
+		case "export":
+			return runExport(args[1:], stdout)
+		case "rebuild-index":
+			return runRebuildIndex(args[1:], stdout)
+		case "snapshot":
+			return runSnapshot(args[1:], stdout)
+		}
+	}
+	return runAnalyze(args, stdin, stdout)
+}
+
+func runAnalyze(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("mta-optimizer", flag.ContinueOnError)
+	defaults := config.Default()
+	threshold := flags.Int("threshold", defaults.Threshold, "maximum active MTAs of an inefficient server")
+	ratio := flags.Float64("ratio", 0, "maximum share of active MTAs of an inefficient server, between 0 and 1")
+	minIps := flags.Int("min-ips", 0, "minimum total IPs of an inefficient server")
+	match := flags.String("match", defaults.Match, "combine criteria with and / or")
+	format := flags.String("format", formatTable, "output format: table, json, ndjson or csv")
+	input := addInputFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("at most one input file can be given")
+	}
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	// criteria set on the command line replace the default threshold, as query parameters do for the API
+	rule := models.Rule{Match: *match}
+	if set["threshold"] || !set["ratio"] && !set["min-ips"] {
+		rule.MaxActive = threshold
+	}
+	if set["ratio"] {
+		rule.MaxActiveRatio = ratio
+	}
+	if set["min-ips"] {
+		rule.MinTotalIps = minIps
+	}
+	if *threshold < 0 || *ratio < 0 || *ratio > 1 || *minIps < 0 {
+		return errors.New("threshold and min-ips must be non-negative, ratio must be between 0 and 1")
+	}
+	if rule.Match != models.MatchAll && rule.Match != models.MatchAny {
+		return errors.New("match must be and or or")
+	}
+	if *format != formatTable && !codec.IsFormat(*format) {
+		return errors.New("format must be table, json, ndjson or csv")
+	}
+
+	rawRecords, err := input.read(flags.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	// exports are analysed as they are, records are not validated
+	ipConfig := make([]models.IpConfig, 0, len(rawRecords))
+	for i, rawRecord := range rawRecords {
+		var record models.IpConfig
+		if err := json.Unmarshal(rawRecord, &record); err != nil {
+			return fmt.Errorf("invalid server data: record %d: %v", i, err)
+		}
+		ipConfig = append(ipConfig, record)
+	}
+	resp := inefficientServers(ipConfig, rule)
+	var buf bytes.Buffer
+	switch *format {
+	case codec.FormatJSON:
+		err = writeJSON(&buf, resp)
+	case codec.FormatNDJSON:
+		err = codec.WriteNDJSON(&buf, resp.Servers)
+	case codec.FormatCSV:
+		err = codec.WriteServerCSV(&buf, resp.Servers)
+	default:
+		err = writeTable(&buf, resp.Servers)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(buf.Bytes())
+	return err
+}
+
+// get servers meeting the rule, sorted by hostname
+func inefficientServers(ipConfig []models.IpConfig, rule models.Rule) models.ServerResponse {
+	result := analyzer.Analyze(ipConfig, analyzer.Options{Rule: rule})
+	resp := models.ServerResponse{
+		Hostnames: []string{},
+		Threshold: rule.MaxActive,
+		Rule:      rule,
+		Servers:   result.Inefficient,
+	}
+	for _, server := range result.Inefficient {
+		resp.Hostnames = append(resp.Hostnames, server.Hostname)
+	}
+	return resp
+}
+
+// write value as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeTable(w io.Writer, servers []models.ServerDetail) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "HOSTNAME\tACTIVE\tINACTIVE\tTOTAL")
+	for _, server := range servers {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\n", server.Hostname, server.ActiveMTAs, server.InactiveMTAs, server.TotalIps)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+const mockServerJsonData = `[
+	{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true},
+	{"ip":"10.0.0.2","hostname":"DummyHostname2","active":true},
+	{"ip":"10.0.0.3","hostname":"DummyHostname2","active":false},
+	{"ip":"10.0.0.4","hostname":"DummyHostname3","active":true},
+	{"ip":"10.0.0.5","hostname":"DummyHostname3","active":true}
+]`
+
+func Test_run_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Table",
+			args:     []string{},
+			expected: "HOSTNAME        ACTIVE  INACTIVE  TOTAL\nDummyHostname1  1       0         1\nDummyHostname2  1       1         2\n",
+		},
+		{
+			name:     "CSV",
+			args:     []string{"--format", "csv", "--threshold", "2", "-"},
+			expected: "hostname,activeMTAs,inactiveMTAs,totalIps,policy,datacenter,pool\nDummyHostname1,1,0,1,,,\nDummyHostname2,1,1,2,,,\nDummyHostname3,2,0,2,,,\n",
+		},
+		{
+			name:     "JSON",
+			args:     []string{"-format", "json", "-min-ips", "2", "-ratio", "0.5"},
+			expected: "{\n  \"hostnames\": [\n    \"DummyHostname2\"\n  ],\n  \"rule\": {\n    \"maxActiveRatio\": 0.5,\n    \"minTotalIps\": 2,\n    \"match\": \"and\"\n  },\n  \"servers\": [\n    {\n      \"hostname\": \"DummyHostname2\",\n      \"activeMTAs\": 1,\n      \"inactiveMTAs\": 1,\n      \"totalIps\": 2,\n      \"ips\": [\n        \"10.0.0.2\",\n        \"10.0.0.3\"\n      ]\n    }\n  ]\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := run(tt.args, strings.NewReader(mockServerJsonData), &stdout)
+			assert.Nil(t, err)
+			assert.Equal(t, stdout.String(), tt.expected)
+		})
+	}
+}
+
+func Test_run_Fail(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+	}{
+		{name: "Invalid format", args: []string{"--format", "xml"}, input: mockServerJsonData},
+		{name: "Invalid threshold", args: []string{"--threshold", "-1"}, input: mockServerJsonData},
+		{name: "Invalid match", args: []string{"--match", "xor"}, input: mockServerJsonData},
+		{name: "Missing file", args: []string{"missing.json"}, input: mockServerJsonData},
+		{name: "Invalid data", args: []string{}, input: "Invalid Data"},
+		{name: "Invalid input format", args: []string{"--input-format", "xml"}, input: mockServerJsonData},
+		{name: "Invalid columns", args: []string{"--input-format", "csv", "--columns", "port=Port"}, input: "ip,hostname,active\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := run(tt.args, strings.NewReader(tt.input), &stdout)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func Test_run_CSVInput_Success(t *testing.T) {
+	input := "Address,Server,Status,Owner\n10.0.0.1,DummyHostname1,true,ops\n10.0.0.2,DummyHostname1,false,ops\n"
+	var stdout bytes.Buffer
+	err := run([]string{"--input-format", "csv", "--columns", "ip=Address,hostname=Server,active=Status", "--format", "ndjson"}, strings.NewReader(input), &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), `{"hostname":"DummyHostname1","activeMTAs":1,"inactiveMTAs":1,"totalIps":2,"ips":["10.0.0.1","10.0.0.2"]}`+"\n")
+}
+
+func Test_run_ImportExport_Success(t *testing.T) {
+	repo := repository.NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	openRepository = func() (repositories, error) {
+		return repositories{ipConfig: repo}, nil
+	}
+	defer func() { openRepository = openConfiguredRepository }()
+
+	var stdout bytes.Buffer
+	err := run([]string{"import", "--input-format", "ndjson"}, strings.NewReader(`{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true}`+"\n"), &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), "imported 1 records: 1 created, 0 updated\n")
+
+	stdout.Reset()
+	err = run([]string{"import", "--input-format", "csv"}, strings.NewReader("ip,hostname,active\n10.0.0.1,DummyHostname1,false\n10.0.0.2,DummyHostname2,true\n"), &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), "imported 2 records: 1 created, 1 updated\n")
+
+	stdout.Reset()
+	err = run([]string{"export", "--format", "csv"}, nil, &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), "ip,hostname,active\n10.0.0.1,DummyHostname1,false\n10.0.0.2,DummyHostname2,true\n")
+
+	// nothing is stored if any record is invalid
+	err = run([]string{"import", "--input-format", "csv"}, strings.NewReader("ip,hostname,active\n10.0.0.3,DummyHostname3,true\nInvalid,DummyHostname3,yes\n"), &stdout)
+	assert.Equal(t, err.Error(), "invalid server data:\n  [1].ip: ip must be a valid IPv4 or IPv6 address\n  [1].active: active must be true or false")
+	ipConfig, _ := repo.Load()
+	assert.Equal(t, len(ipConfig), 2)
+
+	stdout.Reset()
+	err = run([]string{"import", "--replace", "--input-format", "ndjson"}, strings.NewReader(`{"ip":"10.0.0.3","hostname":"DummyHostname3","active":true}`+"\n"), &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), "replaced all records with 1 records\n")
+	ipConfig, _ = repo.Load()
+	assert.Equal(t, ipConfig, []models.IpConfig{{Ip: "10.0.0.3", Hostname: "DummyHostname3", Active: true}})
+}
+
+func Test_run_RebuildIndex_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	indexRepo := repository.NewIndexRepository(store, "index.json")
+	// records written without the index
+	repo := repository.NewIpConfigRepository(store, "ipConfig.json", repository.WithShards(2, 2))
+	repo.Append(
+		models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: false},
+	)
+	openRepository = func() (repositories, error) {
+		return repositories{ipConfig: repo, index: indexRepo}, nil
+	}
+	defer func() { openRepository = openConfiguredRepository }()
+
+	var stdout bytes.Buffer
+	err := run([]string{"rebuild-index"}, nil, &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), "rebuilt index of 2 hosts\n")
+	counts, _ := indexRepo.Load()
+	assert.Equal(t, counts, []models.HostCount{
+		{Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1},
+		{Hostname: "DummyHostname2", InactiveMTAs: 1},
+	})
+}
+
+// repository written to while its records are streamed
+type writingRepository struct {
+	repository.IpConfigRepository
+}
+
+func (r writingRepository) Stream(read func(key string, r io.Reader) error) errorlib.Error {
+	r.Append(models.IpConfig{Ip: "10.0.0.9", Hostname: "DummyHostname9", Active: true})
+	return r.IpConfigRepository.Stream(read)
+}
+
+func Test_run_RebuildIndex_ConcurrentWrite_Fail(t *testing.T) {
+	store := storage.NewMemoryStore()
+	indexRepo := repository.NewIndexRepository(store, "index.json")
+	repo := repository.NewIpConfigRepository(store, "ipConfig.json", repository.WithIndex(indexRepo))
+	repo.Append(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+	repository.RebuildIndex(repo, indexRepo)
+	openRepository = func() (repositories, error) {
+		return repositories{ipConfig: writingRepository{repo}, index: indexRepo}, nil
+	}
+	defer func() { openRepository = openConfiguredRepository }()
+
+	var stdout bytes.Buffer
+	err := run([]string{"rebuild-index"}, nil, &stdout)
+	assert.Equal(t, err.Error(), "server data was written during the rebuild. Please rebuild the index again")
+	// the index keeps the counts of the concurrent write
+	counts, _ := indexRepo.Load()
+	assert.Equal(t, counts, []models.HostCount{
+		{Hostname: "DummyHostname1", ActiveMTAs: 1},
+		{Hostname: "DummyHostname9", ActiveMTAs: 1},
+	})
+}
+
+func Test_run_Snapshot_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	snapshots := repository.NewSnapshotRepository(store, "snapshots/", 0)
+	// appends to sharded data take no snapshots
+	repo := repository.NewIpConfigRepository(store, "ipConfig.json", repository.WithSnapshots(snapshots), repository.WithShards(2, 2))
+	repo.Append(
+		models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: false},
+	)
+	openRepository = func() (repositories, error) {
+		return repositories{ipConfig: repo, snapshots: snapshots}, nil
+	}
+	defer func() { openRepository = openConfiguredRepository }()
+
+	var stdout bytes.Buffer
+	err := run([]string{"snapshot"}, nil, &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), "saved snapshot of 2 records\n")
+	list, _ := snapshots.List()
+	assert.Equal(t, len(list), 1)
+	ipConfig, _ := snapshots.Load(list[0].Id)
+	assert.ElementsMatch(t, ipConfig, []models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: false},
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mta-hosting-optimizer/lib/codec"
+)
+
+// flags selecting how input records are read
+type inputFlags struct {
+	format  *string
+	columns *string
+}
+
+func addInputFlags(flags *flag.FlagSet) inputFlags {
+	return inputFlags{
+		format:  flags.String("input-format", "", "input format: json, ndjson or csv. Defaults to the file extension, json for stdin"),
+		columns: flags.String("columns", "", "mapping of record fields to CSV header names, e.g. ip=IP Address,hostname=Server"),
+	}
+}
+
+// read raw records from file, or from stdin if name is empty or -
+func (f inputFlags) read(name string, stdin io.Reader) ([]json.RawMessage, error) {
+	format := *f.format
+	if format == "" {
+		format = formatOf(name)
+	}
+	columns, err := codec.ParseColumns(*f.columns)
+	if err != nil {
+		return nil, err
+	}
+	input := stdin
+	if name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		input = file
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	var rawRecords []json.RawMessage
+	switch format {
+	case codec.FormatCSV:
+		rawRecords, err = codec.SplitCSV(data, columns)
+	case codec.FormatNDJSON:
+		rawRecords, err = codec.SplitNDJSON(data)
+	case codec.FormatJSON:
+		rawRecords, err = codec.SplitJSON(data)
+	default:
+		return nil, errors.New("input-format must be json, ndjson or csv")
+	}
+	if err != nil {
+		return nil, errors.New("malformed input: " + err.Error())
+	}
+	return rawRecords, nil
+}
+
+// get format of input file from its extension, JSON if unknown
+func formatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return codec.FormatCSV
+	case ".ndjson", ".jsonl":
+		return codec.FormatNDJSON
+	default:
+		return codec.FormatJSON
+	}
+}
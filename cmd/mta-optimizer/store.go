@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mta-hosting-optimizer/lib/codec"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// repositories of the configured storage
+type repositories struct {
+	ipConfig  repository.IpConfigRepository
+	index     repository.IndexRepository
+	snapshots repository.SnapshotRepository
+}
+
+// open repositories of the configured storage, replaced in tests
+var openRepository = openConfiguredRepository
+
+func openConfiguredRepository() (repositories, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return repositories{}, err
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		return repositories{}, err
+	}
+	store, err := storage.New(svc)
+	if err != nil {
+		return repositories{}, err
+	}
+	snapshots := repository.NewSnapshotRepository(store, cfg.SnapshotPrefix, cfg.SnapshotMaxAge())
+	indexRepo := repository.NewIndexRepository(store, cfg.IndexKey)
+	repo := repository.NewIpConfigRepository(store, cfg.Key, repository.WithSnapshots(snapshots), repository.WithIndex(indexRepo), repository.WithShards(cfg.Shards, cfg.ReadConcurrency))
+	return repositories{ipConfig: repo, index: indexRepo, snapshots: snapshots}, nil
+}
+
+// validate records and create or update them in storage using IP as key, as the mock API does
+func runImport(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("mta-optimizer import", flag.ContinueOnError)
+	input := addInputFlags(flags)
+	replace := flags.Bool("replace", false, "replace all stored records with the input, e.g. to repair sharded data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("at most one input file can be given")
+	}
+	rawRecords, err := input.read(flags.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	if len(rawRecords) == 0 {
+		return errors.New("no records to import")
+	}
+	records, details := validator.DecodeIpConfigs(rawRecords)
+	// nothing is stored if any record is invalid
+	if len(details) > 0 {
+		var msg bytes.Buffer
+		msg.WriteString("invalid server data:")
+		for _, detail := range details {
+			fmt.Fprintf(&msg, "\n  %s: %s", detail.Field, detail.Message)
+		}
+		return errors.New(msg.String())
+	}
+	repos, err := openRepository()
+	if err != nil {
+		return err
+	}
+	if *replace {
+		if svcErr := repos.ipConfig.Save(records); svcErr != nil {
+			return svcErr
+		}
+		_, err = fmt.Fprintf(stdout, "replaced all records with %d records\n", len(records))
+		return err
+	}
+	result, svcErr := repos.ipConfig.Upsert(records...)
+	if svcErr != nil {
+		return svcErr
+	}
+	created := 0
+	for _, recordResult := range result.Results {
+		if recordResult.Status == models.StatusCreated {
+			created++
+		}
+	}
+	_, err = fmt.Fprintf(stdout, "imported %d records: %d created, %d updated\n", len(result.Results), created, len(result.Results)-created)
+	return err
+}
+
+// write all records of storage
+func runExport(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("mta-optimizer export", flag.ContinueOnError)
+	format := flags.String("format", codec.FormatJSON, "output format: json, ndjson or csv")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !codec.IsFormat(*format) {
+		return errors.New("format must be json, ndjson or csv")
+	}
+	repos, err := openRepository()
+	if err != nil {
+		return err
+	}
+	ipConfig, svcErr := repos.ipConfig.Load()
+	if svcErr != nil {
+		return svcErr
+	}
+	var buf bytes.Buffer
+	switch *format {
+	case codec.FormatCSV:
+		err = codec.WriteIpConfigCSV(&buf, ipConfig, nil)
+	case codec.FormatNDJSON:
+		err = codec.WriteNDJSON(&buf, ipConfig)
+	default:
+		err = writeJSON(&buf, ipConfig)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(buf.Bytes())
+	return err
+}
+
+// regenerate the per host counts of the index from all records of storage. The index is only
+// replaced if no records were written while they were read
+func runRebuildIndex(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("mta-optimizer rebuild-index", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	repos, err := openRepository()
+	if err != nil {
+		return err
+	}
+	hosts, svcErr := repository.RebuildIndex(repos.ipConfig, repos.index)
+	if svcErr != nil {
+		return svcErr
+	}
+	_, err = fmt.Fprintf(stdout, "rebuilt index of %d hosts\n", hosts)
+	return err
+}
+
+// store all records of storage as a new snapshot, e.g. for sharded data whose appends and upserts
+// take no snapshots
+func runSnapshot(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("mta-optimizer snapshot", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	repos, err := openRepository()
+	if err != nil {
+		return err
+	}
+	ipConfig, svcErr := repos.ipConfig.Load()
+	if svcErr != nil {
+		return svcErr
+	}
+	if svcErr := repos.snapshots.Save(time.Now(), ipConfig); svcErr != nil {
+		return svcErr
+	}
+	_, err = fmt.Fprintf(stdout, "saved snapshot of %d records\n", len(ipConfig))
+	return err
+}
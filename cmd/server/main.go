@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	getconsolidationplan "github.com/mta-hosting-optimizer/api/getConsolidationPlan"
+	getinefficientservers "github.com/mta-hosting-optimizer/api/getInefficientServers"
+	getsnapshotdiff "github.com/mta-hosting-optimizer/api/getSnapshotDiff"
+	getsubnetutilisation "github.com/mta-hosting-optimizer/api/getSubnetUtilisation"
+	gettrends "github.com/mta-hosting-optimizer/api/getTrends"
+	"github.com/mta-hosting-optimizer/api/hosts"
+	ipconfigs "github.com/mta-hosting-optimizer/api/ipConfigs"
+	addmockdata "github.com/mta-hosting-optimizer/api/mockService/addMockData"
+	getmockdata "github.com/mta-hosting-optimizer/api/mockService/getMockData"
+	"github.com/mta-hosting-optimizer/api/policies"
+	"github.com/mta-hosting-optimizer/lib/config"
+	httpadapter "github.com/mta-hosting-optimizer/lib/httpAdapter"
+	"github.com/mta-hosting-optimizer/lib/repository"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	port := flag.String("port", getEnv("PORT", "8080"), "port to listen on")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	svc, err := service.NewService(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	// all handlers share one store so that the memory backend serves consistent data
+	store, err := storage.New(svc)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	snapshots := repository.NewSnapshotRepository(store, cfg.SnapshotPrefix, cfg.SnapshotMaxAge())
+	indexRepo := repository.NewIndexRepository(store, cfg.IndexKey)
+	repo := repository.NewIpConfigRepository(store, cfg.Key, repository.WithSnapshots(snapshots), repository.WithIndex(indexRepo), repository.WithShards(cfg.Shards, cfg.ReadConcurrency))
+	policyRepo := repository.NewPolicyRepository(store, cfg.PolicyKey)
+	hostRepo := repository.NewHostRepository(store, cfg.HostKey)
+	mux := httpadapter.NewServeMux([]httpadapter.Route{
+		{Method: http.MethodGet, Path: "/inefficient-servers", Handler: getinefficientservers.NewHandler(svc, repo, policyRepo, hostRepo, indexRepo)},
+		{Method: http.MethodGet, Path: "/consolidation-plan", Handler: getconsolidationplan.NewHandler(svc, repo, policyRepo, hostRepo)},
+		{Method: http.MethodGet, Path: "/subnet-utilisation", Handler: getsubnetutilisation.NewHandler(repo)},
+		{Method: http.MethodGet, Path: "/trends", Handler: gettrends.NewHandler(svc, snapshots)},
+		{Method: http.MethodGet, Path: "/snapshot-diff", Handler: getsnapshotdiff.NewHandler(svc, snapshots)},
+		{Method: http.MethodPost, Path: "/mock-data", Handler: addmockdata.NewHandler(repo)},
+		{Method: http.MethodGet, Path: "/mock-data", Handler: getmockdata.NewHandler(repo)},
+		{Method: http.MethodGet, Path: "/ip-configs", Handler: ipconfigs.NewHandler(repo)},
+		{Method: http.MethodGet, Path: "/ip-configs/{ip}", Handler: ipconfigs.NewHandler(repo)},
+		{Method: http.MethodPut, Path: "/ip-configs/{ip}", Handler: ipconfigs.NewHandler(repo)},
+		{Method: http.MethodPatch, Path: "/ip-configs/{ip}", Handler: ipconfigs.NewHandler(repo)},
+		{Method: http.MethodDelete, Path: "/ip-configs/{ip}", Handler: ipconfigs.NewHandler(repo)},
+		{Method: http.MethodGet, Path: "/policies", Handler: policies.NewHandler(policyRepo)},
+		{Method: http.MethodPut, Path: "/policies", Handler: policies.NewHandler(policyRepo)},
+		{Method: http.MethodGet, Path: "/hosts", Handler: hosts.NewHandler(hostRepo)},
+		{Method: http.MethodGet, Path: "/hosts/{hostname}", Handler: hosts.NewHandler(hostRepo)},
+		{Method: http.MethodPut, Path: "/hosts/{hostname}", Handler: hosts.NewHandler(hostRepo)},
+		{Method: http.MethodDelete, Path: "/hosts/{hostname}", Handler: hosts.NewHandler(hostRepo)},
+	})
+	server := &http.Server{
+		Addr:              ":" + *port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("listening on %s", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("%v", err)
+		}
+	}()
+	<-ctx.Done()
+
+	// stop accepting requests and wait for in-flight requests to complete
+	log.Printf("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
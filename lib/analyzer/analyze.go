@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// inputs of an analysis besides the server data
+type Options struct {
+	Rule       models.Rule       // rule of hosts matching no policy
+	Policies   []models.Policy   // evaluated in order, the first matching policy applies
+	Hosts      []models.Host     // metadata of hosts, joined by hostname
+	HostFilter filter.HostFilter // only hosts matching the filter are evaluated
+}
+
+// outcome of an analysis
+type Result struct {
+	Rule         models.Rule
+	Servers      []models.ServerDetail // evaluated servers, sorted by hostname
+	Inefficient  []models.ServerDetail // servers meeting the rule of their policy, sorted by hostname
+	ActiveMTAs   int                   // totals of the evaluated servers
+	InactiveMTAs int
+	TotalIps     int
+}
+
+// evaluate every host of the server data against the rule of its policy, hosts matching no policy
+// are evaluated against the rule of the options. Servers carry their host metadata, and the name of
+// their policy if policies are given
+func Analyze(ipConfig []models.IpConfig, opts Options) Result {
+	return AnalyzeServers(MakeIpConfigMap(ipConfig), opts)
+}
+
+// evaluate servers aggregated with MakeIpConfigMap or Aggregate, as Analyze does
+func AnalyzeServers(serverMap map[string]*models.ServerDetail, opts Options) Result {
+	hostMap := make(map[string]models.Host, len(opts.Hosts))
+	for _, host := range opts.Hosts {
+		hostMap[host.Hostname] = host
+	}
+	result := Result{
+		Rule:        opts.Rule,
+		Servers:     []models.ServerDetail{},
+		Inefficient: []models.ServerDetail{},
+	}
+	for _, server := range serverMap {
+		host := hostMap[server.Hostname]
+		if !opts.HostFilter.Match(host) {
+			continue
+		}
+		server.Datacenter = host.Datacenter
+		server.Pool = host.Pool
+		server.Capacity = host.Capacity
+		server.Tags = host.Tags
+		serverRule := opts.Rule
+		if policy, ok := MatchPolicy(opts.Policies, server.Hostname, host.Tags); ok {
+			serverRule = policy.Rule
+			server.Policy = policy.Name
+		} else if len(opts.Policies) > 0 {
+			// the policy is only reported if policies are defined
+			server.Policy = models.DefaultPolicy
+		}
+		result.Servers = append(result.Servers, *server)
+		result.ActiveMTAs += server.ActiveMTAs
+		result.InactiveMTAs += server.InactiveMTAs
+		result.TotalIps += server.TotalIps
+		if IsInefficient(*server, serverRule) {
+			result.Inefficient = append(result.Inefficient, *server)
+		}
+	}
+	sortByHostname(result.Servers)
+	sortByHostname(result.Inefficient)
+	return result
+}
+
+func sortByHostname(servers []models.ServerDetail) {
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Hostname < servers[j].Hostname
+	})
+}
@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// make map of server with active MTA information
+func MakeIpConfigMap(ipConfig []models.IpConfig) map[string]*models.ServerDetail {
+	serverMap := make(map[string]*models.ServerDetail)
+	for _, val := range ipConfig {
+		addRecord(serverMap, val, true)
+	}
+	return serverMap
+}
+
+// make map of server with active MTA information from a JSON array of records, decoding one record
+// at a time so that memory is proportional to the number of hosts rather than records. IPs of the
+// servers are only collected if withIps is set
+func Aggregate(r io.Reader, withIps bool) (map[string]*models.ServerDetail, error) {
+	serverMap := make(map[string]*models.ServerDetail)
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	// saving no records stores null
+	if token == nil {
+		return serverMap, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("server data must be a JSON array")
+	}
+	for decoder.More() {
+		var record models.IpConfig
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		addRecord(serverMap, record, withIps)
+	}
+	// consume closing bracket, reporting truncated data
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return serverMap, nil
+}
+
+// add MTA usage of servers in src to the servers in dst, e.g. to combine servers aggregated from
+// separate parts of the server data
+func Merge(dst map[string]*models.ServerDetail, src map[string]*models.ServerDetail) {
+	for hostname, server := range src {
+		existing, ok := dst[hostname]
+		if !ok {
+			dst[hostname] = server
+			continue
+		}
+		existing.ActiveMTAs += server.ActiveMTAs
+		existing.InactiveMTAs += server.InactiveMTAs
+		existing.TotalIps += server.TotalIps
+		existing.Ips = append(existing.Ips, server.Ips...)
+	}
+}
+
+func addRecord(serverMap map[string]*models.ServerDetail, record models.IpConfig, withIps bool) {
+	server, ok := serverMap[record.Hostname]
+	if !ok {
+		server = &models.ServerDetail{Hostname: record.Hostname}
+		serverMap[record.Hostname] = server
+	}
+	if record.Active {
+		server.ActiveMTAs++
+	} else {
+		server.InactiveMTAs++
+	}
+	server.TotalIps++
+	if withIps {
+		server.Ips = append(server.Ips, record.Ip)
+	}
+}
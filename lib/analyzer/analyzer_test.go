@@ -0,0 +1,432 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mta-hosting-optimizer/lib/filter"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MakeIpConfigMap_Success(t *testing.T) {
+	ipConfig := []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "DummyIP3", Hostname: "DummyHostname1", Active: true},
+	}
+	result := MakeIpConfigMap(ipConfig)
+	assert.Equal(t, result, map[string]*models.ServerDetail{
+		"DummyHostname1": {
+			Hostname:     "DummyHostname1",
+			ActiveMTAs:   2,
+			InactiveMTAs: 1,
+			TotalIps:     3,
+			Ips:          []string{"DummyIP1", "DummyIP2", "DummyIP3"},
+		},
+	})
+}
+
+func Test_ConsolidationPlan_Success(t *testing.T) {
+	ipConfig := []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP2", Hostname: "DummyHostname2", Active: true},
+		{Ip: "DummyIP3", Hostname: "DummyHostname2", Active: false},
+		{Ip: "DummyIP4", Hostname: "DummyHostname3", Active: true},
+		{Ip: "DummyIP5", Hostname: "DummyHostname3", Active: true},
+		{Ip: "DummyIP6", Hostname: "DummyHostname3", Active: true},
+	}
+	threshold := 1
+	rule := models.Rule{MaxActive: &threshold}
+	result := ConsolidationPlan(ipConfig, 4, Options{Rule: rule})
+	assert.Equal(t, result, models.ConsolidationPlan{
+		Threshold:           &threshold,
+		Rule:                rule,
+		Capacity:            4,
+		DecommissionCount:   1,
+		DecommissionedHosts: []string{"DummyHostname1"},
+		UnresolvedHosts:     []string{"DummyHostname2"},
+		Moves: []models.Move{
+			{Ip: "DummyIP1", From: "DummyHostname1", To: "DummyHostname3"},
+		},
+	})
+}
+
+func Test_ConsolidationPlan_NoCapacity_Success(t *testing.T) {
+	ipConfig := []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP2", Hostname: "DummyHostname2", Active: true},
+		{Ip: "DummyIP3", Hostname: "DummyHostname2", Active: true},
+	}
+	threshold := 1
+	result := ConsolidationPlan(ipConfig, 2, Options{Rule: models.Rule{MaxActive: &threshold}})
+	assert.Equal(t, result.DecommissionCount, 0)
+	assert.Equal(t, result.UnresolvedHosts, []string{"DummyHostname1"})
+	assert.Equal(t, result.Moves, []models.Move{})
+}
+
+func Test_ConsolidationPlan_FillReceivers_Success(t *testing.T) {
+	var ipConfig []models.IpConfig
+	for hostname, active := range map[string]int{"DummyHostname1": 3, "DummyHostname2": 2, "DummyHostname3": 1, "DummyHostname4": 1} {
+		for i := 0; i < active; i++ {
+			ipConfig = append(ipConfig, models.IpConfig{Ip: hostname + "-" + strconv.Itoa(i), Hostname: hostname, Active: true})
+		}
+	}
+	threshold := 1
+	result := ConsolidationPlan(ipConfig, 4, Options{Rule: models.Rule{MaxActive: &threshold}})
+	// the fullest host is filled before the next one receives MTAs
+	assert.Equal(t, result.Moves, []models.Move{
+		{Ip: "DummyHostname3-0", From: "DummyHostname3", To: "DummyHostname1"},
+		{Ip: "DummyHostname4-0", From: "DummyHostname4", To: "DummyHostname2"},
+	})
+	assert.Equal(t, result.DecommissionedHosts, []string{"DummyHostname3", "DummyHostname4"})
+}
+
+func Test_ConsolidationPlan_HostCapacity_Success(t *testing.T) {
+	var ipConfig []models.IpConfig
+	for hostname, active := range map[string]int{"DummyHostname1": 3, "DummyHostname2": 2, "DummyHostname3": 1} {
+		for i := 0; i < active; i++ {
+			ipConfig = append(ipConfig, models.IpConfig{Ip: hostname + "-" + strconv.Itoa(i), Hostname: hostname, Active: true})
+		}
+	}
+	threshold := 1
+	// the fullest host is already at its declared capacity, the global capacity applies to the others
+	hosts := []models.Host{{Hostname: "DummyHostname1", Capacity: 3}}
+	result := ConsolidationPlan(ipConfig, 4, Options{Rule: models.Rule{MaxActive: &threshold}, Hosts: hosts})
+	assert.Equal(t, result.Moves, []models.Move{
+		{Ip: "DummyHostname3-0", From: "DummyHostname3", To: "DummyHostname2"},
+	})
+}
+
+func Test_SubnetUtilisation_Success(t *testing.T) {
+	ipConfig := []models.IpConfig{
+		{Ip: "10.0.1.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.1.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.1", Hostname: "DummyHostname2", Active: true},
+		{Ip: "2001:db8::1", Hostname: "DummyHostname3", Active: false},
+		{Ip: "::ffff:10.0.0.2", Hostname: "DummyHostname2", Active: true},
+		{Ip: "DummyIP1", Hostname: "DummyHostname3", Active: true},
+	}
+	result := SubnetUtilisation(ipConfig, DefaultPrefixV4, DefaultPrefixV6, 0.5)
+	assert.Equal(t, result, models.SubnetReport{
+		Ratio:    0.5,
+		PrefixV4: 24,
+		PrefixV6: 64,
+		Subnets: []models.SubnetDetail{
+			{Subnet: "10.0.0.0/24", ActiveMTAs: 2, TotalIps: 2, ActiveRatio: 1},
+			{Subnet: "10.0.1.0/24", ActiveMTAs: 1, InactiveMTAs: 1, TotalIps: 2, ActiveRatio: 0.5},
+			{Subnet: "2001:db8::/64", InactiveMTAs: 1, TotalIps: 1, ActiveRatio: 0, Flagged: true},
+		},
+		FlaggedSubnets: []string{"2001:db8::/64"},
+		UnparsedIps:    []string{"DummyIP1"},
+	})
+}
+
+func Test_SubnetUtilisation_Prefix_Success(t *testing.T) {
+	ipConfig := []models.IpConfig{
+		{Ip: "10.0.1.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.2.1", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.3.1", Hostname: "DummyHostname1", Active: false},
+	}
+	result := SubnetUtilisation(ipConfig, 16, DefaultPrefixV6, 0.5)
+	assert.Equal(t, result.Subnets, []models.SubnetDetail{
+		{Subnet: "10.0.0.0/16", ActiveMTAs: 1, InactiveMTAs: 2, TotalIps: 3, ActiveRatio: 1.0 / 3, Flagged: true},
+	})
+	assert.Equal(t, result.FlaggedSubnets, []string{"10.0.0.0/16"})
+}
+
+func Test_IsInefficient(t *testing.T) {
+	one, four, quarter := 1, 4, 0.25
+	// two hosts with one active MTA, the second one is the less utilised
+	small := models.ServerDetail{Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1, TotalIps: 2}
+	large := models.ServerDetail{Hostname: "DummyHostname2", ActiveMTAs: 1, InactiveMTAs: 9, TotalIps: 10}
+	tests := []struct {
+		name     string
+		rule     models.Rule
+		expected []bool
+	}{
+		{name: "Threshold", rule: models.Rule{MaxActive: &one}, expected: []bool{true, true}},
+		{name: "Ratio", rule: models.Rule{MaxActiveRatio: &quarter}, expected: []bool{false, true}},
+		{name: "Minimum IPs", rule: models.Rule{MinTotalIps: &four}, expected: []bool{false, true}},
+		{name: "Threshold and minimum IPs", rule: models.Rule{MaxActive: &one, MinTotalIps: &four, Match: models.MatchAll}, expected: []bool{false, true}},
+		{name: "Ratio or minimum IPs", rule: models.Rule{MaxActiveRatio: &quarter, MinTotalIps: &four, Match: models.MatchAny}, expected: []bool{false, true}},
+		{name: "Threshold or ratio", rule: models.Rule{MaxActive: &one, MaxActiveRatio: &quarter, Match: models.MatchAny}, expected: []bool{true, true}},
+		{name: "No criteria", rule: models.Rule{}, expected: []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, []bool{IsInefficient(small, tt.rule), IsInefficient(large, tt.rule)}, tt.expected)
+		})
+	}
+}
+
+func Test_MatchPolicy(t *testing.T) {
+	policies := []models.Policy{
+		{Name: "warm-up", Tags: []string{"warm-up", "marketing"}},
+		{Name: "marketing", Hostname: "mta-marketing-*"},
+		{Name: "all", Hostname: "*"},
+	}
+	tests := []struct {
+		hostname string
+		tags     []string
+		expected string
+	}{
+		{hostname: "mta-marketing-1", tags: []string{"marketing", "warm-up"}, expected: "warm-up"},
+		{hostname: "mta-marketing-1", tags: []string{"marketing"}, expected: "marketing"},
+		{hostname: "mta-transactional-1", expected: "all"},
+	}
+	for _, tt := range tests {
+		policy, ok := MatchPolicy(policies, tt.hostname, tt.tags)
+		assert.True(t, ok)
+		assert.Equal(t, policy.Name, tt.expected)
+	}
+	_, ok := MatchPolicy(policies[:2], "mta-transactional-1", nil)
+	assert.False(t, ok)
+}
+
+func Test_Trends_Success(t *testing.T) {
+	maxActive := 1
+	rule := models.Rule{MaxActive: &maxActive, Match: models.MatchAll}
+	snapshot := func(id int, ipConfig ...models.IpConfig) SnapshotRecords {
+		takenAt := time.Date(2024, 1, 1, id, 0, 0, 0, time.UTC)
+		return SnapshotRecords{Snapshot: models.Snapshot{Id: takenAt.Format("20060102T15"), TakenAt: takenAt}, IpConfig: ipConfig}
+	}
+	snapshots := []SnapshotRecords{
+		snapshot(1,
+			models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+			models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: true},
+		),
+		snapshot(2,
+			models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+			models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname1", Active: true},
+		),
+		snapshot(3,
+			models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+			models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: true},
+		),
+		snapshot(4,
+			models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false},
+			models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: true},
+		),
+	}
+	trends := Trends(snapshots, rule)
+	assert.Equal(t, len(trends), 2)
+	assert.Equal(t, trends[0].Hostname, "DummyHostname1")
+	// inefficient in the latest two snapshots only, the second one had two active MTAs
+	assert.Equal(t, trends[0].InefficientStreak, 2)
+	assert.Equal(t, len(trends[0].Points), 4)
+	assert.Equal(t, trends[0].Points[1], models.TrendPoint{
+		SnapshotId: snapshots[1].Snapshot.Id,
+		TakenAt:    snapshots[1].Snapshot.TakenAt,
+		ActiveMTAs: 2,
+		TotalIps:   2,
+	})
+	// missing from the second snapshot
+	assert.Equal(t, trends[1].Hostname, "DummyHostname2")
+	assert.Equal(t, trends[1].InefficientStreak, 2)
+	assert.Equal(t, len(trends[1].Points), 3)
+}
+
+func Test_Diff_Success(t *testing.T) {
+	maxActive := 1
+	rule := models.Rule{MaxActive: &maxActive, Match: models.MatchAll}
+	from := []models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.4", Hostname: "DummyHostname2", Active: false},
+	}
+	to := []models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname3", Active: true},
+		{Ip: "10.0.0.5", Hostname: "DummyHostname3", Active: true},
+	}
+	assert.Equal(t, Diff(from, to, rule), models.SnapshotDiff{
+		Rule:                rule,
+		AddedIps:            []models.IpConfig{{Ip: "10.0.0.5", Hostname: "DummyHostname3", Active: true}},
+		RemovedIps:          []models.IpConfig{{Ip: "10.0.0.4", Hostname: "DummyHostname2", Active: false}},
+		Reassigned:          []models.Reassignment{{Ip: "10.0.0.3", From: "DummyHostname2", To: "DummyHostname3"}},
+		ActiveChanged:       []models.ActiveChange{{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false}},
+		BecameInefficient:   []string{"DummyHostname1"},
+		NoLongerInefficient: []string{"DummyHostname2"},
+	})
+}
+
+func Test_Analyze_Success(t *testing.T) {
+	maxActive := 1
+	noActive := 0
+	ipConfig := []models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.4", Hostname: "DummyHostname3", Active: true},
+		{Ip: "10.0.0.5", Hostname: "DummyHostname3", Active: true},
+	}
+	result := Analyze(ipConfig, Options{
+		Rule:     models.Rule{MaxActive: &maxActive, Match: models.MatchAll},
+		Policies: []models.Policy{{Name: "strict", Tags: []string{"strict"}, Rule: models.Rule{MaxActive: &noActive}}},
+		Hosts: []models.Host{
+			{Hostname: "DummyHostname1", Datacenter: "dc1", Tags: []string{"strict"}},
+			{Hostname: "DummyHostname2", Datacenter: "dc1"},
+			{Hostname: "DummyHostname3", Datacenter: "dc2"},
+		},
+	})
+	assert.Equal(t, len(result.Servers), 3)
+	assert.Equal(t, []int{result.ActiveMTAs, result.InactiveMTAs, result.TotalIps}, []int{4, 1, 5})
+	assert.Equal(t, result.Inefficient, []models.ServerDetail{
+		{Hostname: "DummyHostname2", ActiveMTAs: 1, TotalIps: 1, Ips: []string{"10.0.0.1"}, Policy: models.DefaultPolicy, Datacenter: "dc1"},
+	})
+
+	// without policies only the rule applies, hosts not matching the filter are skipped
+	result = Analyze(ipConfig, Options{
+		Rule:       models.Rule{MaxActive: &maxActive, Match: models.MatchAll},
+		Hosts:      []models.Host{{Hostname: "DummyHostname1", Datacenter: "dc1"}, {Hostname: "DummyHostname2", Datacenter: "dc1"}},
+		HostFilter: filter.HostFilter{Datacenter: "dc1"},
+	})
+	assert.Equal(t, len(result.Servers), 2)
+	assert.Equal(t, len(result.Inefficient), 2)
+	assert.Equal(t, result.Inefficient[0].Hostname, "DummyHostname1")
+	assert.Equal(t, result.Inefficient[0].Policy, "")
+}
+
+func Test_Aggregate_Success(t *testing.T) {
+	data := `[
+		{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true},
+		{"ip":"10.0.0.2","hostname":"DummyHostname1","active":false},
+		{"ip":"10.0.0.3","hostname":"DummyHostname2","active":true}
+	]`
+	serverMap, err := Aggregate(strings.NewReader(data), false)
+	assert.Nil(t, err)
+	assert.Equal(t, serverMap, map[string]*models.ServerDetail{
+		"DummyHostname1": {Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1, TotalIps: 2},
+		"DummyHostname2": {Hostname: "DummyHostname2", ActiveMTAs: 1, TotalIps: 1},
+	})
+
+	// with IPs the result equals the map of decoded records
+	var ipConfig []models.IpConfig
+	json.Unmarshal([]byte(data), &ipConfig)
+	serverMap, err = Aggregate(strings.NewReader(data), true)
+	assert.Nil(t, err)
+	assert.Equal(t, serverMap, MakeIpConfigMap(ipConfig))
+
+	serverMap, err = Aggregate(strings.NewReader("null"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, len(serverMap), 0)
+}
+
+func Test_Aggregate_Fail(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Empty", data: ""},
+		{name: "Object", data: `{"ip":"10.0.0.1"}`},
+		{name: "Invalid record", data: `[{"ip":"10.0.0.1","active":"yes"}]`},
+		{name: "Truncated", data: `[{"ip":"10.0.0.1","hostname":"DummyHostname1","active":true},`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Aggregate(strings.NewReader(tt.data), false)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func Test_Merge_Success(t *testing.T) {
+	serverMap := MakeIpConfigMap([]models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+	})
+	Merge(serverMap, MakeIpConfigMap([]models.IpConfig{
+		{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+	}))
+	assert.Equal(t, serverMap, map[string]*models.ServerDetail{
+		"DummyHostname1": {Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1, TotalIps: 2, Ips: []string{"10.0.0.1", "10.0.0.2"}},
+		"DummyHostname2": {Hostname: "DummyHostname2", ActiveMTAs: 1, TotalIps: 1, Ips: []string{"10.0.0.3"}},
+	})
+}
+
+// server data of a fleet of 1000 hosts
+func makeFleet(records int) []byte {
+	var data strings.Builder
+	data.WriteString("[")
+	for i := 0; i < records; i++ {
+		if i > 0 {
+			data.WriteString(",")
+		}
+		fmt.Fprintf(&data, `{"ip":"10.%d.%d.%d","hostname":"mta-prod-%d","active":%t}`, i>>16&255, i>>8&255, i&255, i%1000, i%3 == 0)
+	}
+	data.WriteString("]")
+	return []byte(data.String())
+}
+
+// heap still in use by the result of f after garbage collection
+func retainedHeap(f func() interface{}) float64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	result := f()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(result)
+	return float64(after.HeapAlloc) - float64(before.HeapAlloc)
+}
+
+// compare decoding all records before aggregating them with aggregating while decoding. The heap
+// retained by decoded records grows with the number of records, the heap retained by aggregation
+// only with the number of hosts
+func Benchmark_Aggregate(b *testing.B) {
+	for _, records := range []int{10000, 100000} {
+		data := makeFleet(records)
+		// compare both approaches with and without collecting IPs of the servers
+		for _, withIps := range []bool{false, true} {
+			mode := "Counts"
+			if withIps {
+				mode = "Ips"
+			}
+			b.Run(fmt.Sprintf("Unmarshal_%s_%d", mode, records), func(b *testing.B) {
+				decode := func() interface{} {
+					var ipConfig []models.IpConfig
+					if err := json.Unmarshal(data, &ipConfig); err != nil {
+						b.Fatal(err)
+					}
+					serverMap := make(map[string]*models.ServerDetail)
+					for _, val := range ipConfig {
+						addRecord(serverMap, val, withIps)
+					}
+					return []interface{}{ipConfig, serverMap}
+				}
+				retained := retainedHeap(decode)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					decode()
+				}
+				b.ReportMetric(retained, "retained-B")
+			})
+			b.Run(fmt.Sprintf("Stream_%s_%d", mode, records), func(b *testing.B) {
+				aggregate := func() interface{} {
+					serverMap, err := Aggregate(bytes.NewReader(data), withIps)
+					if err != nil {
+						b.Fatal(err)
+					}
+					return serverMap
+				}
+				retained := retainedHeap(aggregate)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					aggregate()
+				}
+				b.ReportMetric(retained, "retained-B")
+			})
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// host taking part in the consolidation plan
+type planHost struct {
+	hostname       string
+	capacity       int
+	activeIps      []string
+	received       bool
+	decommissioned bool
+}
+
+// compute a plan moving active MTAs off the inefficient hosts of the analysis onto the fullest
+// evaluated hosts that still have free capacity, so that the emptied hosts can be decommissioned.
+// Hosts with capacity metadata hold at most that many active MTAs, other hosts at most capacity
+func ConsolidationPlan(ipConfig []models.IpConfig, capacity int, opts Options) models.ConsolidationPlan {
+	result := Analyze(ipConfig, opts)
+	plan := models.ConsolidationPlan{
+		Threshold:           opts.Rule.MaxActive,
+		Rule:                opts.Rule,
+		Capacity:            capacity,
+		DecommissionedHosts: []string{},
+		UnresolvedHosts:     []string{},
+		Moves:               []models.Move{},
+	}
+	// only evaluated hosts take part, hosts excluded by the host filter are left untouched
+	hosts := make(map[string]*planHost, len(result.Servers))
+	for _, server := range result.Servers {
+		host := &planHost{hostname: server.Hostname, capacity: capacity}
+		if server.Capacity > 0 {
+			host.capacity = server.Capacity
+		}
+		hosts[server.Hostname] = host
+	}
+	for _, val := range ipConfig {
+		if host, ok := hosts[val.Hostname]; ok && val.Active {
+			host.activeIps = append(host.activeIps, val.Ip)
+		}
+	}
+	// empty the least utilised hosts first
+	candidates := make([]*planHost, 0, len(result.Inefficient))
+	for _, server := range result.Inefficient {
+		candidates = append(candidates, hosts[server.Hostname])
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].activeIps) != len(candidates[j].activeIps) {
+			return len(candidates[i].activeIps) < len(candidates[j].activeIps)
+		}
+		return candidates[i].hostname < candidates[j].hostname
+	})
+	// hosts with free capacity, fullest first so that MTAs are packed onto as few hosts as possible.
+	// Only the first receiver gains MTAs and stays the fullest, so the order holds without resorting
+	var receivers []*planHost
+	free := 0
+	for _, host := range hosts {
+		if len(host.activeIps) < host.capacity {
+			receivers = append(receivers, host)
+			free += host.capacity - len(host.activeIps)
+		}
+	}
+	sort.Slice(receivers, func(i, j int) bool {
+		if len(receivers[i].activeIps) != len(receivers[j].activeIps) {
+			return len(receivers[i].activeIps) > len(receivers[j].activeIps)
+		}
+		return receivers[i].hostname < receivers[j].hostname
+	})
+	for _, donor := range candidates {
+		// hosts which received MTAs in this plan are no longer consolidation candidates
+		if donor.received {
+			continue
+		}
+		donorFree := 0
+		if len(donor.activeIps) < donor.capacity {
+			donorFree = donor.capacity - len(donor.activeIps)
+		}
+		if free-donorFree < len(donor.activeIps) {
+			plan.UnresolvedHosts = append(plan.UnresolvedHosts, donor.hostname)
+			continue
+		}
+		donor.decommissioned = true
+		free -= donorFree
+		for _, ip := range donor.activeIps {
+			receivers = dropUnavailable(receivers)
+			receiver := receivers[0]
+			receiver.activeIps = append(receiver.activeIps, ip)
+			receiver.received = true
+			free--
+			plan.Moves = append(plan.Moves, models.Move{Ip: ip, From: donor.hostname, To: receiver.hostname})
+		}
+		donor.activeIps = nil
+		plan.DecommissionedHosts = append(plan.DecommissionedHosts, donor.hostname)
+	}
+	plan.DecommissionCount = len(plan.DecommissionedHosts)
+	return plan
+}
+
+// drop full and decommissioned hosts from the front of the receivers. Hosts never become available
+// again, so every host is dropped at most once
+func dropUnavailable(receivers []*planHost) []*planHost {
+	for len(receivers) > 0 && (receivers[0].decommissioned || len(receivers[0].activeIps) >= receivers[0].capacity) {
+		receivers = receivers[1:]
+	}
+	return receivers
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// compare records of two snapshots using IP as key, IPs stored more than once count with their last record.
+// Hosts are compared by whether they are inefficient as per rule
+func Diff(from []models.IpConfig, to []models.IpConfig, rule models.Rule) models.SnapshotDiff {
+	diff := models.SnapshotDiff{
+		Rule:                rule,
+		AddedIps:            []models.IpConfig{},
+		RemovedIps:          []models.IpConfig{},
+		Reassigned:          []models.Reassignment{},
+		ActiveChanged:       []models.ActiveChange{},
+		BecameInefficient:   []string{},
+		NoLongerInefficient: []string{},
+	}
+	fromMap := makeIpMap(from)
+	toMap := makeIpMap(to)
+	for _, ip := range sortedIps(toMap) {
+		record := toMap[ip]
+		previous, ok := fromMap[ip]
+		if !ok {
+			diff.AddedIps = append(diff.AddedIps, record)
+			continue
+		}
+		if previous.Hostname != record.Hostname {
+			diff.Reassigned = append(diff.Reassigned, models.Reassignment{Ip: ip, From: previous.Hostname, To: record.Hostname})
+		}
+		if previous.Active != record.Active {
+			diff.ActiveChanged = append(diff.ActiveChanged, models.ActiveChange{Ip: ip, Hostname: record.Hostname, Active: record.Active})
+		}
+	}
+	for _, ip := range sortedIps(fromMap) {
+		if _, ok := toMap[ip]; !ok {
+			diff.RemovedIps = append(diff.RemovedIps, fromMap[ip])
+		}
+	}
+	fromInefficient := inefficientHosts(from, rule)
+	toInefficient := inefficientHosts(to, rule)
+	for hostname := range toInefficient {
+		if !fromInefficient[hostname] {
+			diff.BecameInefficient = append(diff.BecameInefficient, hostname)
+		}
+	}
+	for hostname := range fromInefficient {
+		if !toInefficient[hostname] {
+			diff.NoLongerInefficient = append(diff.NoLongerInefficient, hostname)
+		}
+	}
+	sort.Strings(diff.BecameInefficient)
+	sort.Strings(diff.NoLongerInefficient)
+	return diff
+}
+
+// index records by IP, the last record of an IP wins
+func makeIpMap(ipConfig []models.IpConfig) map[string]models.IpConfig {
+	ipMap := make(map[string]models.IpConfig, len(ipConfig))
+	for _, record := range ipConfig {
+		ipMap[record.Ip] = record
+	}
+	return ipMap
+}
+
+func sortedIps(ipMap map[string]models.IpConfig) []string {
+	ips := make([]string, 0, len(ipMap))
+	for ip := range ipMap {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// set of hostnames which are inefficient as per rule
+func inefficientHosts(ipConfig []models.IpConfig, rule models.Rule) map[string]bool {
+	hosts := make(map[string]bool)
+	for hostname, server := range MakeIpConfigMap(ipConfig) {
+		if IsInefficient(*server, rule) {
+			hosts[hostname] = true
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"path"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// get the first policy matching hostname and tags of a host
+func MatchPolicy(policies []models.Policy, hostname string, tags []string) (models.Policy, bool) {
+	for _, policy := range policies {
+		if policyMatches(policy, hostname, tags) {
+			return policy, true
+		}
+	}
+	return models.Policy{}, false
+}
+
+// check if host matches the hostname pattern and has all tags of the policy
+func policyMatches(policy models.Policy, hostname string, tags []string) bool {
+	if policy.Hostname != "" {
+		// patterns are checked when policies are saved
+		if matched, _ := path.Match(policy.Hostname, hostname); !matched {
+			return false
+		}
+	}
+	for _, tag := range policy.Tags {
+		if !hasTag(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
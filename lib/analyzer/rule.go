@@ -0,0 +1,38 @@
+package analyzer
+
+import "github.com/mta-hosting-optimizer/lib/models"
+
+// check if server meets all criteria of the rule, or any of them if the rule matches any
+func IsInefficient(server models.ServerDetail, rule models.Rule) bool {
+	var results []bool
+	if rule.MaxActive != nil {
+		results = append(results, server.ActiveMTAs <= *rule.MaxActive)
+	}
+	if rule.MaxActiveRatio != nil {
+		results = append(results, activeRatio(server) <= *rule.MaxActiveRatio)
+	}
+	if rule.MinTotalIps != nil {
+		results = append(results, server.TotalIps >= *rule.MinTotalIps)
+	}
+	// a rule without criteria matches nothing
+	if len(results) == 0 {
+		return false
+	}
+	for _, result := range results {
+		if rule.Match == models.MatchAny && result {
+			return true
+		}
+		if rule.Match != models.MatchAny && !result {
+			return false
+		}
+	}
+	return rule.Match != models.MatchAny
+}
+
+// share of active MTAs of a server, a server without IPs has none
+func activeRatio(server models.ServerDetail) float64 {
+	if server.TotalIps == 0 {
+		return 0
+	}
+	return float64(server.ActiveMTAs) / float64(server.TotalIps)
+}
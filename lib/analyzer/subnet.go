@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// default subnet sizes, MTA reputation is usually managed per /24 and per /64
+const (
+	DefaultPrefixV4 = 24
+	DefaultPrefixV6 = 64
+)
+
+// group records by subnet of the given prefix lengths and flag subnets whose share of active MTAs is below ratio
+func SubnetUtilisation(ipConfig []models.IpConfig, prefixV4 int, prefixV6 int, ratio float64) models.SubnetReport {
+	report := models.SubnetReport{
+		Ratio:          ratio,
+		PrefixV4:       prefixV4,
+		PrefixV6:       prefixV6,
+		Subnets:        []models.SubnetDetail{},
+		FlaggedSubnets: []string{},
+	}
+	subnetMap := make(map[netip.Prefix]*models.SubnetDetail)
+	for _, val := range ipConfig {
+		addr, err := netip.ParseAddr(val.Ip)
+		if err != nil {
+			report.UnparsedIps = append(report.UnparsedIps, val.Ip)
+			continue
+		}
+		addr = addr.Unmap().WithZone("")
+		bits := prefixV6
+		if addr.Is4() {
+			bits = prefixV4
+		}
+		// prefix lengths are checked by the caller
+		subnet, _ := addr.Prefix(bits)
+		detail, ok := subnetMap[subnet]
+		if !ok {
+			detail = &models.SubnetDetail{Subnet: subnet.String()}
+			subnetMap[subnet] = detail
+		}
+		if val.Active {
+			detail.ActiveMTAs++
+		} else {
+			detail.InactiveMTAs++
+		}
+		detail.TotalIps++
+	}
+	subnets := make([]netip.Prefix, 0, len(subnetMap))
+	for subnet := range subnetMap {
+		subnets = append(subnets, subnet)
+	}
+	// IPv4 subnets sort before IPv6 subnets
+	sort.Slice(subnets, func(i, j int) bool {
+		return subnets[i].Addr().Less(subnets[j].Addr())
+	})
+	for _, subnet := range subnets {
+		detail := subnetMap[subnet]
+		detail.ActiveRatio = float64(detail.ActiveMTAs) / float64(detail.TotalIps)
+		detail.Flagged = detail.ActiveRatio < ratio
+		if detail.Flagged {
+			report.FlaggedSubnets = append(report.FlaggedSubnets, detail.Subnet)
+		}
+		report.Subnets = append(report.Subnets, *detail)
+	}
+	return report
+}
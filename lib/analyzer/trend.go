@@ -0,0 +1,80 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// server data of a snapshot
+type SnapshotRecords struct {
+	Snapshot models.Snapshot
+	IpConfig []models.IpConfig
+}
+
+// active MTAs of every host over snapshots ordered oldest first, sorted by hostname. The streak of a host
+// counts the latest snapshots in a row in which it was present and inefficient as per rule
+func Trends(snapshots []SnapshotRecords, rule models.Rule) []models.HostTrend {
+	builder := NewTrendBuilder(rule)
+	for _, snapshot := range snapshots {
+		builder.Add(snapshot.Snapshot, snapshot.IpConfig)
+	}
+	return builder.Trends()
+}
+
+// aggregation of trends one snapshot at a time, only the per host counts of every snapshot are kept
+type TrendBuilder struct {
+	rule      models.Rule
+	snapshots []models.Snapshot
+	trendMap  map[string]*models.HostTrend
+}
+
+func NewTrendBuilder(rule models.Rule) *TrendBuilder {
+	return &TrendBuilder{rule: rule, trendMap: make(map[string]*models.HostTrend)}
+}
+
+// add the records of a snapshot taken after every snapshot added before
+func (b *TrendBuilder) Add(snapshot models.Snapshot, ipConfig []models.IpConfig) {
+	b.snapshots = append(b.snapshots, snapshot)
+	for hostname, server := range MakeIpConfigMap(ipConfig) {
+		trend, ok := b.trendMap[hostname]
+		if !ok {
+			trend = &models.HostTrend{Hostname: hostname}
+			b.trendMap[hostname] = trend
+		}
+		trend.Points = append(trend.Points, models.TrendPoint{
+			SnapshotId:  snapshot.Id,
+			TakenAt:     snapshot.TakenAt,
+			ActiveMTAs:  server.ActiveMTAs,
+			TotalIps:    server.TotalIps,
+			Inefficient: IsInefficient(*server, b.rule),
+		})
+	}
+}
+
+// trends of every host over the snapshots added so far, sorted by hostname
+func (b *TrendBuilder) Trends() []models.HostTrend {
+	trends := make([]models.HostTrend, 0, len(b.trendMap))
+	for _, trend := range b.trendMap {
+		trend.InefficientStreak = streak(*trend, b.snapshots)
+		trends = append(trends, *trend)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Hostname < trends[j].Hostname
+	})
+	return trends
+}
+
+// count latest snapshots in a row in which host was inefficient, a snapshot without the host ends the streak
+func streak(trend models.HostTrend, snapshots []models.Snapshot) int {
+	count := 0
+	p := len(trend.Points) - 1
+	for s := len(snapshots) - 1; s >= 0; s-- {
+		if p < 0 || trend.Points[p].SnapshotId != snapshots[s].Id || !trend.Points[p].Inefficient {
+			break
+		}
+		count++
+		p--
+	}
+	return count
+}
@@ -1,14 +1,19 @@
 package dummy
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	s3Svc "github.com/aws/aws-sdk-go/service/s3"
 	"github.com/mta-hosting-optimizer/lib/aws/s3"
 )
 
 type S3Interface struct {
-	DummyGetObject  func(*s3Svc.GetObjectInput) (*s3Svc.GetObjectOutput, error)
-	DummyPutObject  func(*s3Svc.PutObjectInput) (*s3Svc.PutObjectOutput, error)
-	DummyHeadObject func(*s3Svc.HeadObjectInput) (*s3Svc.HeadObjectOutput, error)
+	DummyGetObject            func(*s3Svc.GetObjectInput) (*s3Svc.GetObjectOutput, error)
+	DummyPutObject            func(*s3Svc.PutObjectInput) (*s3Svc.PutObjectOutput, error)
+	DummyHeadObject           func(*s3Svc.HeadObjectInput) (*s3Svc.HeadObjectOutput, error)
+	DummyPutObjectWithContext func(aws.Context, *s3Svc.PutObjectInput, ...request.Option) (*s3Svc.PutObjectOutput, error)
+	DummyListObjectsV2        func(*s3Svc.ListObjectsV2Input) (*s3Svc.ListObjectsV2Output, error)
+	DummyDeleteObject         func(*s3Svc.DeleteObjectInput) (*s3Svc.DeleteObjectOutput, error)
 }
 
 var _ s3.Interface = &S3Interface{}
@@ -19,6 +24,15 @@ func (d S3Interface) GetObject(input *s3Svc.GetObjectInput) (*s3Svc.GetObjectOut
 func (d S3Interface) PutObject(input *s3Svc.PutObjectInput) (*s3Svc.PutObjectOutput, error) {
 	return d.DummyPutObject(input)
 }
+func (d S3Interface) PutObjectWithContext(ctx aws.Context, input *s3Svc.PutObjectInput, opts ...request.Option) (*s3Svc.PutObjectOutput, error) {
+	return d.DummyPutObjectWithContext(ctx, input, opts...)
+}
 func (d S3Interface) HeadObject(input *s3Svc.HeadObjectInput) (*s3Svc.HeadObjectOutput, error) {
 	return d.DummyHeadObject(input)
 }
+func (d S3Interface) ListObjectsV2(input *s3Svc.ListObjectsV2Input) (*s3Svc.ListObjectsV2Output, error) {
+	return d.DummyListObjectsV2(input)
+}
+func (d S3Interface) DeleteObject(input *s3Svc.DeleteObjectInput) (*s3Svc.DeleteObjectOutput, error) {
+	return d.DummyDeleteObject(input)
+}
@@ -1,6 +1,8 @@
 package s3
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -9,7 +11,10 @@ import (
 type Interface interface {
 	GetObject(*s3.GetObjectInput) (*s3.GetObjectOutput, error)
 	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+	PutObjectWithContext(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
 	HeadObject(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(*s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+	DeleteObject(*s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error)
 }
 
 type service struct {
@@ -28,6 +33,15 @@ func (svc *service) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, er
 func (svc *service) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
 	return svc.s3.PutObject(input)
 }
+func (svc *service) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	return svc.s3.PutObjectWithContext(ctx, input, opts...)
+}
 func (svc *service) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
 	return svc.s3.HeadObject(input)
 }
+func (svc *service) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	return svc.s3.ListObjectsV2(input)
+}
+func (svc *service) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	return svc.s3.DeleteObject(input)
+}
@@ -0,0 +1,67 @@
+package codec
+
+import (
+	"errors"
+	"strings"
+)
+
+// supported formats of imported and exported records
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// content types of the formats
+const (
+	JSONContentType   = "application/json"
+	NDJSONContentType = "application/x-ndjson"
+	CSVContentType    = "text/csv"
+)
+
+var contentTypes = map[string]string{
+	FormatJSON:   JSONContentType,
+	FormatNDJSON: NDJSONContentType,
+	FormatCSV:    CSVContentType,
+}
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// check if format is supported
+func IsFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// get content type of format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// get format of content type, parameters like charset are ignored
+func FormatOf(contentType string) (string, bool) {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for format, formatType := range contentTypes {
+		if mediaType == formatType {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// choose format from the format parameter or else the accept header. The first supported media type
+// of the accept header wins, JSON is returned if none is supported
+func Negotiate(format string, accept string) (string, error) {
+	if format != "" {
+		if !IsFormat(format) {
+			return "", ErrUnsupportedFormat
+		}
+		return format, nil
+	}
+	for _, mediaType := range strings.Split(accept, ",") {
+		if format, ok := FormatOf(mediaType); ok {
+			return format, nil
+		}
+	}
+	return FormatJSON, nil
+}
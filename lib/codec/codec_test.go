@@ -0,0 +1,106 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Negotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		accept   string
+		expected string
+	}{
+		{name: "Default", expected: FormatJSON},
+		{name: "Format parameter wins", format: FormatNDJSON, accept: "text/csv", expected: FormatNDJSON},
+		{name: "First supported media type", accept: "text/html, text/csv;q=0.8, application/json", expected: FormatCSV},
+		{name: "Unsupported media types", accept: "text/html, */*", expected: FormatJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Negotiate(tt.format, tt.accept)
+			assert.Nil(t, err)
+			assert.Equal(t, format, tt.expected)
+		})
+	}
+	_, err := Negotiate("xml", "")
+	assert.Equal(t, err, ErrUnsupportedFormat)
+}
+
+func Test_ParseColumns_Success(t *testing.T) {
+	columns, err := ParseColumns("ip=IP Address, hostname = Server")
+	assert.Nil(t, err)
+	assert.Equal(t, columns, map[string]string{"ip": "IP Address", "hostname": "Server"})
+}
+
+func Test_ParseColumns_Fail(t *testing.T) {
+	for _, value := range []string{"ip", "ip=", "port=Port"} {
+		_, err := ParseColumns(value)
+		assert.NotNil(t, err)
+	}
+}
+
+func Test_SplitCSV_Success(t *testing.T) {
+	data := "\ufeffServer,IP,Active,Owner\nDummyHostname1,10.0.0.1,TRUE,ops\nDummyHostname1,10.0.0.2,no,ops\n,10.0.0.3,,ops\n"
+	rawRecords, err := SplitCSV([]byte(data), map[string]string{"hostname": "Server"})
+	assert.Nil(t, err)
+	var records []string
+	for _, rawRecord := range rawRecords {
+		records = append(records, string(rawRecord))
+	}
+	assert.Equal(t, records, []string{
+		`{"active":true,"hostname":"DummyHostname1","ip":"10.0.0.1"}`,
+		`{"active":"no","hostname":"DummyHostname1","ip":"10.0.0.2"}`,
+		`{"ip":"10.0.0.3"}`,
+	})
+}
+
+func Test_SplitCSV_Fail(t *testing.T) {
+	_, err := SplitCSV([]byte("ip,hostname\n10.0.0.1,DummyHostname1,true\n"), nil)
+	assert.NotNil(t, err)
+	_, err = SplitCSV([]byte("ip,hostname\n"), map[string]string{"active": "Status"})
+	assert.Equal(t, err.Error(), `column "Status" of field active not found in header`)
+}
+
+func Test_SplitJSON_Success(t *testing.T) {
+	for _, data := range []string{
+		`[{"ip":"10.0.0.1"},{"ip":"10.0.0.2"}]`,
+		"{\"ip\":\"10.0.0.1\"}\n{\"ip\":\"10.0.0.2\"}",
+	} {
+		rawRecords, err := SplitJSON([]byte(data))
+		assert.Nil(t, err)
+		assert.Equal(t, rawRecords, []json.RawMessage{json.RawMessage(`{"ip":"10.0.0.1"}`), json.RawMessage(`{"ip":"10.0.0.2"}`)})
+	}
+}
+
+func Test_SplitNDJSON_Success(t *testing.T) {
+	rawRecords, err := SplitNDJSON([]byte("{\"ip\":\"10.0.0.1\"}\n\n{\"ip\":\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, rawRecords, []json.RawMessage{json.RawMessage(`{"ip":"10.0.0.1"}`), json.RawMessage(`{"ip":`)})
+}
+
+func Test_Write_Success(t *testing.T) {
+	ipConfig := []models.IpConfig{
+		{Ip: "10.0.0.1", Hostname: "mta-prod,1", Active: true},
+		{Ip: "10.0.0.2", Hostname: "mta-prod-2", Active: false},
+	}
+	var buf bytes.Buffer
+	err := WriteIpConfigCSV(&buf, ipConfig, []string{"active", "hostname"})
+	assert.Nil(t, err)
+	assert.Equal(t, buf.String(), "active,hostname\ntrue,\"mta-prod,1\"\nfalse,mta-prod-2\n")
+
+	buf.Reset()
+	err = WriteNDJSON(&buf, ipConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, buf.String(), `{"ip":"10.0.0.1","hostname":"mta-prod,1","active":true}`+"\n"+`{"ip":"10.0.0.2","hostname":"mta-prod-2","active":false}`+"\n")
+
+	buf.Reset()
+	err = WriteServerCSV(&buf, []models.ServerDetail{{Hostname: "mta-prod-1", ActiveMTAs: 1, InactiveMTAs: 2, TotalIps: 3, Policy: "default", Datacenter: "dc1"}})
+	assert.Nil(t, err)
+	assert.Equal(t, buf.String(), "hostname,activeMTAs,inactiveMTAs,totalIps,policy,datacenter,pool\nmta-prod-1,1,2,3,default,dc1,\n")
+}
@@ -0,0 +1,152 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// columns of server data records, in export order
+var IpConfigColumns = []string{"ip", "hostname", "active"}
+
+// columns of exported servers
+var ServerColumns = []string{"hostname", "activeMTAs", "inactiveMTAs", "totalIps", "policy", "datacenter", "pool"}
+
+// parse mapping of record fields to CSV header names, e.g. ip=IP Address,hostname=Server
+func ParseColumns(value string) (map[string]string, error) {
+	columns := make(map[string]string)
+	if value == "" {
+		return columns, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		header = strings.TrimSpace(header)
+		if !ok || field == "" || header == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected field=header", pair)
+		}
+		if !isIpConfigColumn(field) {
+			return nil, fmt.Errorf("unknown field %q in column mapping", field)
+		}
+		columns[field] = header
+	}
+	return columns, nil
+}
+
+func isIpConfigColumn(field string) bool {
+	for _, column := range IpConfigColumns {
+		if column == field {
+			return true
+		}
+	}
+	return false
+}
+
+// split CSV data with a header row into raw JSON records, one per row. Fields are read from the header
+// named in columns, or else from the header named like the field ignoring case. Other columns are ignored,
+// empty cells leave the field unset and active values which are not booleans are kept as text,
+// so that the validator reports them
+func SplitCSV(data []byte, columns map[string]string) ([]json.RawMessage, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for _, field := range IpConfigColumns {
+		name, mapped := columns[field]
+		found := false
+		for i, column := range header {
+			// spreadsheet exports may start with a byte order mark
+			column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+			if mapped && column == name || !mapped && strings.EqualFold(column, field) {
+				index[field] = i
+				found = true
+				break
+			}
+		}
+		if mapped && !found {
+			return nil, fmt.Errorf("column %q of field %s not found in header", name, field)
+		}
+	}
+	var rawRecords []json.RawMessage
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rawRecords, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]interface{})
+		for field, i := range index {
+			if i >= len(row) || strings.TrimSpace(row[i]) == "" {
+				continue
+			}
+			value := strings.TrimSpace(row[i])
+			record[field] = value
+			if field == "active" {
+				if active, err := strconv.ParseBool(value); err == nil {
+					record[field] = active
+				}
+			}
+		}
+		rawRecord, _ := json.Marshal(record)
+		rawRecords = append(rawRecords, rawRecord)
+	}
+}
+
+// write records as CSV with a header row. Only the given columns are written, all columns if none are given
+func WriteIpConfigCSV(w io.Writer, ipConfig []models.IpConfig, columns []string) error {
+	if len(columns) == 0 {
+		columns = IpConfigColumns
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+	for _, record := range ipConfig {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			switch column {
+			case "ip":
+				row = append(row, record.Ip)
+			case "hostname":
+				row = append(row, record.Hostname)
+			case "active":
+				row = append(row, strconv.FormatBool(record.Active))
+			}
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// write MTA usage of servers as CSV with a header row
+func WriteServerCSV(w io.Writer, servers []models.ServerDetail) error {
+	writer := csv.NewWriter(w)
+	writer.Write(ServerColumns)
+	for _, server := range servers {
+		writer.Write([]string{
+			server.Hostname,
+			strconv.Itoa(server.ActiveMTAs),
+			strconv.Itoa(server.InactiveMTAs),
+			strconv.Itoa(server.TotalIps),
+			server.Policy,
+			server.Datacenter,
+			server.Pool,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,62 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// split JSON data into raw records. Data may hold a JSON array of records or a stream of JSON values,
+// which covers both a single record and compact newline delimited records
+func SplitJSON(data []byte) ([]json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var rawRecords []json.RawMessage
+		if err := json.Unmarshal(data, &rawRecords); err != nil {
+			return nil, err
+		}
+		return rawRecords, nil
+	}
+	var rawRecords []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var rawRecord json.RawMessage
+		err := decoder.Decode(&rawRecord)
+		if errors.Is(err, io.EOF) {
+			return rawRecords, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rawRecords = append(rawRecords, rawRecord)
+	}
+}
+
+// split newline delimited data into raw records without parsing them, so that a malformed line
+// can be reported as an invalid record. Empty lines are skipped
+func SplitNDJSON(data []byte) ([]json.RawMessage, error) {
+	var rawRecords []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rawRecords = append(rawRecords, json.RawMessage(append([]byte(nil), line...)))
+	}
+	return rawRecords, scanner.Err()
+}
+
+// write every record as a line of JSON
+func WriteNDJSON[T any](w io.Writer, records []T) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+	"gopkg.in/yaml.v3"
+)
+
+// environment variables overriding configuration values
+const (
+	FileKey                  = "config_file" // path of an optional YAML or JSON configuration file
+	BucketKey                = "bucket"
+	KeyKey                   = "key"
+	PolicyKeyKey             = "policy_key"
+	HostKeyKey               = "host_key"
+	SnapshotPrefixKey        = "snapshot_prefix"
+	SnapshotRetentionDaysKey = "snapshot_retention_days"
+	IndexKeyKey              = "index_key"
+	RegionKey                = "region"
+	ThresholdKey             = "threshold"
+	StorageKey               = "storage"
+	StoragePathKey           = "storage_path"
+	ShardsKey                = "shards"
+	ReadConcurrencyKey       = "read_concurrency"
+	// additional criteria of the inefficient server rule
+	MaxActiveRatioKey = "max_active_ratio"
+	MinTotalIpsKey    = "min_total_ips"
+	MatchKey          = "match"
+)
+
+// maximum number of shards of server data
+const MaxShards = 1024
+
+// supported storage backends
+const (
+	StorageS3     = "s3"
+	StorageFile   = "file"
+	StorageMemory = "memory"
+)
+
+type Config struct {
+	Bucket                string `json:"bucket" yaml:"bucket"`                               // bucket name must be unique
+	Key                   string `json:"key" yaml:"key"`                                     // key of the server data object
+	PolicyKey             string `json:"policyKey" yaml:"policyKey"`                         // key of the threshold policies object
+	HostKey               string `json:"hostKey" yaml:"hostKey"`                             // key of the host metadata object
+	SnapshotPrefix        string `json:"snapshotPrefix" yaml:"snapshotPrefix"`               // key prefix of server data snapshots
+	SnapshotRetentionDays int    `json:"snapshotRetentionDays" yaml:"snapshotRetentionDays"` // days snapshots are kept, 0 keeps all
+	IndexKey              string `json:"indexKey" yaml:"indexKey"`                           // key of the per host MTA counts of server data
+	Region                string `json:"region" yaml:"region"`
+	Threshold             int    `json:"threshold" yaml:"threshold"` // maximum active MTAs of an inefficient server
+	Storage               string `json:"storage" yaml:"storage"`
+	StoragePath           string `json:"storagePath" yaml:"storagePath"`         // data directory of the file storage backend
+	Shards                int    `json:"shards" yaml:"shards"`                   // number of objects server data is spread across, 0 keeps a single object
+	ReadConcurrency       int    `json:"readConcurrency" yaml:"readConcurrency"` // maximum number of shards read or written at a time
+	// optional criteria combined with threshold to mark a server as inefficient
+	MaxActiveRatio *float64 `json:"maxActiveRatio" yaml:"maxActiveRatio"` // maximum share of active MTAs
+	MinTotalIps    *int     `json:"minTotalIps" yaml:"minTotalIps"`       // minimum total IPs
+	Match          string   `json:"match" yaml:"match"`                   // "and" or "or"
+}
+
+func Default() Config {
+	return Config{
+		Bucket:                "mta-hosting-bucket",
+		Key:                   "ipConfig.json",
+		PolicyKey:             "policies.json",
+		HostKey:               "hosts.json",
+		SnapshotPrefix:        "snapshots/",
+		SnapshotRetentionDays: 30,
+		IndexKey:              "index.json",
+		Region:                "ap-south-1",
+		Threshold:             1,
+		Storage:               StorageS3,
+		StoragePath:           "data",
+		ReadConcurrency:       8,
+		Match:                 models.MatchAll,
+	}
+}
+
+// rule marking a server as inefficient, made of threshold and the optional criteria
+func (c Config) Rule() models.Rule {
+	threshold := c.Threshold
+	return models.Rule{
+		MaxActive:      &threshold,
+		MaxActiveRatio: c.MaxActiveRatio,
+		MinTotalIps:    c.MinTotalIps,
+		Match:          c.Match,
+	}
+}
+
+// age beyond which snapshots are deleted, 0 keeps all snapshots
+func (c Config) SnapshotMaxAge() time.Duration {
+	return time.Duration(c.SnapshotRetentionDays) * 24 * time.Hour
+}
+
+// load configuration from defaults, the optional configuration file and environment variables, in increasing precedence
+func Load() (Config, error) {
+	cfg := Default()
+	if path := os.Getenv(FileKey); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// check configuration values, all violations are reported
+func (c Config) Validate() error {
+	var errs []error
+	if c.Key == "" {
+		errs = append(errs, errors.New("key cannot be empty"))
+	}
+	if c.PolicyKey == "" {
+		errs = append(errs, errors.New("policy key cannot be empty"))
+	}
+	if c.HostKey == "" {
+		errs = append(errs, errors.New("host key cannot be empty"))
+	}
+	if c.SnapshotPrefix == "" {
+		errs = append(errs, errors.New("snapshot prefix cannot be empty"))
+	}
+	if c.SnapshotRetentionDays < 0 {
+		errs = append(errs, errors.New("snapshot retention cannot be negative"))
+	}
+	if c.IndexKey == "" {
+		errs = append(errs, errors.New("index key cannot be empty"))
+	}
+	if c.Threshold < 0 {
+		errs = append(errs, errors.New("threshold cannot be negative"))
+	}
+	if c.Shards < 0 || c.Shards > MaxShards {
+		errs = append(errs, fmt.Errorf("shards must be between 0 and %d", MaxShards))
+	}
+	if c.ReadConcurrency < 1 {
+		errs = append(errs, errors.New("read concurrency must be positive"))
+	}
+	if c.MaxActiveRatio != nil && (*c.MaxActiveRatio < 0 || *c.MaxActiveRatio > 1) {
+		errs = append(errs, errors.New("max active ratio must be between 0 and 1"))
+	}
+	if c.MinTotalIps != nil && *c.MinTotalIps < 0 {
+		errs = append(errs, errors.New("min total ips cannot be negative"))
+	}
+	// an empty match combines criteria with and
+	if c.Match != "" && c.Match != models.MatchAll && c.Match != models.MatchAny {
+		errs = append(errs, fmt.Errorf("unsupported match %q", c.Match))
+	}
+	switch c.Storage {
+	case StorageS3:
+		if c.Bucket == "" {
+			errs = append(errs, errors.New("bucket cannot be empty"))
+		}
+		if c.Region == "" {
+			errs = append(errs, errors.New("region cannot be empty"))
+		}
+	case StorageFile:
+		if c.StoragePath == "" {
+			errs = append(errs, errors.New("storage path cannot be empty"))
+		}
+	case StorageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported storage backend %q", c.Storage))
+	}
+	return errors.Join(errs...)
+}
+
+// overwrite values set in the configuration file, format is chosen by file extension
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported configuration file format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// overwrite values set in environment variables
+func (c *Config) loadEnv() error {
+	setString(&c.Bucket, BucketKey)
+	setString(&c.Key, KeyKey)
+	setString(&c.PolicyKey, PolicyKeyKey)
+	setString(&c.HostKey, HostKeyKey)
+	setString(&c.SnapshotPrefix, SnapshotPrefixKey)
+	setString(&c.IndexKey, IndexKeyKey)
+	setString(&c.Region, RegionKey)
+	setString(&c.Storage, StorageKey)
+	setString(&c.StoragePath, StoragePathKey)
+	setString(&c.Match, MatchKey)
+	if value, ok := os.LookupEnv(ThresholdKey); ok {
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("invalid threshold value")
+		}
+		c.Threshold = threshold
+	}
+	if value, ok := os.LookupEnv(SnapshotRetentionDaysKey); ok {
+		retention, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("invalid snapshot retention value")
+		}
+		c.SnapshotRetentionDays = retention
+	}
+	if value, ok := os.LookupEnv(ShardsKey); ok {
+		shards, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("invalid shards value")
+		}
+		c.Shards = shards
+	}
+	if value, ok := os.LookupEnv(ReadConcurrencyKey); ok {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("invalid read concurrency value")
+		}
+		c.ReadConcurrency = concurrency
+	}
+	if value, ok := os.LookupEnv(MaxActiveRatioKey); ok {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("invalid max active ratio value")
+		}
+		c.MaxActiveRatio = &ratio
+	}
+	if value, ok := os.LookupEnv(MinTotalIpsKey); ok {
+		minTotalIps, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("invalid min total ips value")
+		}
+		c.MinTotalIps = &minTotalIps
+	}
+	return nil
+}
+
+func setString(field *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*field = value
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Load_Default_Success(t *testing.T) {
+	cfg, err := Load()
+	assert.Nil(t, err)
+	assert.Equal(t, cfg, Default())
+}
+
+func Test_Load_Env_Success(t *testing.T) {
+	t.Setenv(BucketKey, "dummy-bucket")
+	t.Setenv(ThresholdKey, "3")
+	t.Setenv(StorageKey, StorageMemory)
+	cfg, err := Load()
+	assert.Nil(t, err)
+	expected := Default()
+	expected.Bucket = "dummy-bucket"
+	expected.Threshold = 3
+	expected.Storage = StorageMemory
+	assert.Equal(t, cfg, expected)
+}
+
+func Test_Load_File_Success(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{name: "YAML file", file: "config.yaml", data: "bucket: dummy-bucket\nthreshold: 2\nstorage: file\nstoragePath: /tmp/dummy\n"},
+		{name: "JSON file", file: "config.json", data: `{"bucket":"dummy-bucket","threshold":2,"storage":"file","storagePath":"/tmp/dummy"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(path, []byte(tt.data), 0o600)
+			t.Setenv(FileKey, path)
+			// environment variables take precedence over the file
+			t.Setenv(ThresholdKey, "4")
+			cfg, err := Load()
+			assert.Nil(t, err)
+			expected := Default()
+			expected.Bucket = "dummy-bucket"
+			expected.Threshold = 4
+			expected.Storage = StorageFile
+			expected.StoragePath = "/tmp/dummy"
+			assert.Equal(t, cfg, expected)
+		})
+	}
+}
+
+func Test_Load_InvalidThreshold_Fail(t *testing.T) {
+	t.Setenv(ThresholdKey, "dummy")
+	_, err := Load()
+	assert.Equal(t, err.Error(), "invalid threshold value")
+}
+
+func Test_Load_InvalidFile_Fail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.txt")
+	os.WriteFile(path, []byte("dummy"), 0o600)
+	t.Setenv(FileKey, path)
+	_, err := Load()
+	assert.Equal(t, err.Error(), `unsupported configuration file format ".txt"`)
+}
+
+func Test_Load_Rule_Success(t *testing.T) {
+	t.Setenv(ThresholdKey, "2")
+	t.Setenv(MaxActiveRatioKey, "0.25")
+	t.Setenv(MinTotalIpsKey, "4")
+	t.Setenv(MatchKey, "or")
+	cfg, err := Load()
+	assert.Nil(t, err)
+	threshold, ratio, minTotalIps := 2, 0.25, 4
+	assert.Equal(t, cfg.Rule(), models.Rule{
+		MaxActive:      &threshold,
+		MaxActiveRatio: &ratio,
+		MinTotalIps:    &minTotalIps,
+		Match:          models.MatchAny,
+	})
+}
+
+func Test_Validate_Rule_Fail(t *testing.T) {
+	ratio, minTotalIps := 1.5, -1
+	cfg := Default()
+	cfg.MaxActiveRatio = &ratio
+	cfg.MinTotalIps = &minTotalIps
+	cfg.Match = "xor"
+	err := cfg.Validate()
+	assert.Equal(t, err.Error(), "max active ratio must be between 0 and 1\nmin total ips cannot be negative\nunsupported match \"xor\"")
+}
+
+func Test_Validate_Fail(t *testing.T) {
+	cfg := Config{Threshold: -1, SnapshotRetentionDays: -1, Storage: StorageS3}
+	err := cfg.Validate()
+	assert.Equal(t, err.Error(), "key cannot be empty\npolicy key cannot be empty\nhost key cannot be empty\nsnapshot prefix cannot be empty\nsnapshot retention cannot be negative\nindex key cannot be empty\nthreshold cannot be negative\nread concurrency must be positive\nbucket cannot be empty\nregion cannot be empty")
+
+	cfg = Default()
+	cfg.Storage = "dummy"
+	err = cfg.Validate()
+	assert.Equal(t, err.Error(), `unsupported storage backend "dummy"`)
+}
@@ -1,8 +1,53 @@
 package constants
 
+// query parameters
 var (
-	Bucket       = "mta-hosting-bucket" //bucket name must be unique. Change this value if you deploy your code
-	Key          = "ipConfig.json"
-	ThresholdKey = "threshold" // environment variable is stored in lambda
-	Region       = "ap-south-1"
+	ThresholdKey   = "threshold" // overrides the configured threshold for a single request
+	DetailedKey    = "detailed"  // return per-host MTA counts
+	SortKey        = "sort"
+	LimitKey       = "limit"
+	CursorKey      = "cursor"
+	CapacityKey    = "capacity" // maximum active MTAs per host in consolidation plan
+	HostnameKey    = "hostname"
+	ActiveKey      = "active"
+	IpKey          = "ip"      // IP prefix or CIDR block
+	FieldsKey      = "fields"  // comma separated record fields to return
+	PrefixKey      = "prefix"  // IPv4 subnet prefix length
+	Prefix6Key     = "prefix6" // IPv6 subnet prefix length
+	RatioKey       = "ratio"   // share of active MTAs
+	MinIpsKey      = "minIps"  // minimum total IPs of an inefficient server
+	MatchKey       = "match"   // combine criteria with and / or
+	DatacenterKey  = "datacenter"
+	PoolKey        = "pool"
+	TagKey         = "tag"
+	GroupByKey     = "groupBy"     // group inefficient servers by datacenter or pool
+	LastKey        = "last"        // number of latest snapshots
+	ConsecutiveKey = "consecutive" // minimum number of latest snapshots a host was inefficient in
+	FromKey        = "from"        // snapshot id to compare from
+	ToKey          = "to"          // snapshot id to compare to
+	FormatKey      = "format"      // json, csv or ndjson, overrides the accept or content type header
+	ColumnsKey     = "columns"     // mapping of record fields to CSV header names, e.g. ip=IP Address
+)
+
+// path parameters
+var (
+	IpPathKey       = "ip"
+	HostnamePathKey = "hostname"
+)
+
+// supported sort orders of inefficient servers
+const (
+	SortByHostname = "hostname"
+	SortByActive   = "active"
+)
+
+// supported groupings of inefficient servers
+const (
+	GroupByDatacenter = "datacenter"
+	GroupByPool       = "pool"
+)
+
+// response headers
+const (
+	NextCursorHeader = "X-Next-Cursor" // cursor of the next page of responses which are not JSON
 )
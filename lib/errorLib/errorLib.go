@@ -3,10 +3,18 @@ package errorlib
 type svcError struct {
 	error      error
 	statusCode int
+	details    []Detail
 }
 type Error interface {
 	Error() string
 	StatusCode() int
+	Details() []Detail
+}
+
+// single violation contributing to an error, e.g. an invalid field of a record
+type Detail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func New(err error, code int) Error {
@@ -15,6 +23,14 @@ func New(err error, code int) Error {
 		statusCode: code,
 	}
 }
+
+func NewWithDetails(err error, code int, details []Detail) Error {
+	return &svcError{
+		error:      err,
+		statusCode: code,
+		details:    details,
+	}
+}
 func (e *svcError) Error() string {
 	return e.error.Error()
 }
@@ -22,3 +38,7 @@ func (e *svcError) Error() string {
 func (e *svcError) StatusCode() int {
 	return e.statusCode
 }
+
+func (e *svcError) Details() []Detail {
+	return e.details
+}
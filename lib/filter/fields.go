@@ -0,0 +1,46 @@
+package filter
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// fields of a record which can be projected, as named in JSON
+var recordFields = map[string]func(models.IpConfig) interface{}{
+	"ip":       func(record models.IpConfig) interface{} { return record.Ip },
+	"hostname": func(record models.IpConfig) interface{} { return record.Hostname },
+	"active":   func(record models.IpConfig) interface{} { return record.Active },
+}
+
+// parse comma separated list of record fields, an empty value selects every field
+func ParseFields(value string) ([]string, errorlib.Error) {
+	if value == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := recordFields[field]; !ok {
+			return nil, errorlib.New(errors.New("invalid fields query parameter. Please provide a comma separated list of ip, hostname, active"), http.StatusBadRequest)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// get records holding only the given fields
+func Project(ipConfig []models.IpConfig, fields []string) []map[string]interface{} {
+	projected := make([]map[string]interface{}, 0, len(ipConfig))
+	for _, record := range ipConfig {
+		values := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			values[field] = recordFields[field](record)
+		}
+		projected = append(projected, values)
+	}
+	return projected
+}
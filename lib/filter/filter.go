@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mta-hosting-optimizer/lib/constants"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// criteria selecting server IP configuration records, empty criteria match every record
+type Filter struct {
+	// hostname or glob pattern of hostnames, e.g. mta-prod-*
+	Hostname string
+	Active   *bool
+	// leading characters of the IP, e.g. 10.0.1.
+	IpPrefix string
+	// network containing the IP, e.g. 10.0.1.0/24
+	Subnet netip.Prefix
+}
+
+// parse filter from query parameters
+func Parse(params map[string]string) (Filter, errorlib.Error) {
+	f := Filter{Hostname: params[constants.HostnameKey]}
+	if _, err := path.Match(f.Hostname, ""); err != nil {
+		return Filter{}, errorlib.New(errors.New("invalid hostname query parameter. Please provide a hostname or a glob pattern"), http.StatusBadRequest)
+	}
+	if value, ok := params[constants.ActiveKey]; ok {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			return Filter{}, errorlib.New(errors.New("invalid active query parameter. Please provide true or false"), http.StatusBadRequest)
+		}
+		f.Active = &active
+	}
+	if value := params[constants.IpKey]; strings.Contains(value, "/") {
+		subnet, err := netip.ParsePrefix(value)
+		if err != nil {
+			return Filter{}, errorlib.New(errors.New("invalid ip query parameter. Please provide an IP prefix or a CIDR block"), http.StatusBadRequest)
+		}
+		f.Subnet = subnet.Masked()
+	} else {
+		f.IpPrefix = value
+	}
+	return f, nil
+}
+
+// check if record matches all criteria
+func (f Filter) Match(record models.IpConfig) bool {
+	if f.Hostname != "" {
+		// the pattern is checked by Parse
+		if matched, _ := path.Match(f.Hostname, record.Hostname); !matched {
+			return false
+		}
+	}
+	if f.Active != nil && record.Active != *f.Active {
+		return false
+	}
+	if !strings.HasPrefix(record.Ip, f.IpPrefix) {
+		return false
+	}
+	if f.Subnet.IsValid() {
+		addr, err := netip.ParseAddr(record.Ip)
+		if err != nil || !f.Subnet.Contains(addr.Unmap()) {
+			return false
+		}
+	}
+	return true
+}
+
+// get records matching all criteria
+func (f Filter) Apply(ipConfig []models.IpConfig) []models.IpConfig {
+	matches := []models.IpConfig{}
+	for _, record := range ipConfig {
+		if f.Match(record) {
+			matches = append(matches, record)
+		}
+	}
+	return matches
+}
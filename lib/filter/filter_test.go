@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockIpConfig = []models.IpConfig{
+	{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+	{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+	{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true},
+}
+
+func Test_Apply_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected []models.IpConfig
+	}{
+		{name: "No filter", params: nil, expected: mockIpConfig},
+		{name: "Hostname filter", params: map[string]string{"hostname": "DummyHostname1"}, expected: mockIpConfig[:2]},
+		{name: "Active filter", params: map[string]string{"active": "true"}, expected: []models.IpConfig{mockIpConfig[0], mockIpConfig[2]}},
+		{name: "Combined filter", params: map[string]string{"hostname": "DummyHostname1", "active": "false"}, expected: mockIpConfig[1:2]},
+		{name: "No match", params: map[string]string{"hostname": "DummyHostname3"}, expected: []models.IpConfig{}},
+		{name: "Hostname glob", params: map[string]string{"hostname": "Dummy*1"}, expected: mockIpConfig[:2]},
+		{name: "IP prefix", params: map[string]string{"ip": "10.0.0.1"}, expected: mockIpConfig[:1]},
+		{name: "CIDR", params: map[string]string{"ip": "10.0.0.2/31"}, expected: mockIpConfig[1:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.params)
+			assert.Nil(t, err)
+			assert.Equal(t, f.Apply(mockIpConfig), tt.expected)
+		})
+	}
+}
+
+func Test_Parse_InvalidActive_Fail(t *testing.T) {
+	_, err := Parse(map[string]string{"active": "dummy"})
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_Parse_InvalidSubnet_Fail(t *testing.T) {
+	_, err := Parse(map[string]string{"ip": "10.0.0.0/dummy"})
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_Project_Success(t *testing.T) {
+	fields, err := ParseFields("hostname, ip")
+	assert.Nil(t, err)
+	assert.Equal(t, Project(mockIpConfig[:1], fields), []map[string]interface{}{
+		{"hostname": "DummyHostname1", "ip": "10.0.0.1"},
+	})
+}
+
+func Test_ParseFields_Fail(t *testing.T) {
+	_, err := ParseFields("ip,")
+	assert.Equal(t, err.StatusCode(), 400)
+}
@@ -0,0 +1,41 @@
+package filter
+
+import (
+	"github.com/mta-hosting-optimizer/lib/constants"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// criteria selecting hosts by metadata, empty criteria match every host
+type HostFilter struct {
+	Datacenter string
+	Pool       string
+	Tag        string
+}
+
+// parse host filter from query parameters
+func ParseHost(params map[string]string) HostFilter {
+	return HostFilter{
+		Datacenter: params[constants.DatacenterKey],
+		Pool:       params[constants.PoolKey],
+		Tag:        params[constants.TagKey],
+	}
+}
+
+// check if host matches all criteria
+func (f HostFilter) Match(host models.Host) bool {
+	if f.Datacenter != "" && host.Datacenter != f.Datacenter {
+		return false
+	}
+	if f.Pool != "" && host.Pool != f.Pool {
+		return false
+	}
+	if f.Tag == "" {
+		return true
+	}
+	for _, tag := range host.Tags {
+		if tag == f.Tag {
+			return true
+		}
+	}
+	return false
+}
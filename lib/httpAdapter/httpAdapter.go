@@ -0,0 +1,185 @@
+package httpadapter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// route served by a lambda handler. Path segments in braces, e.g. /ip-configs/{ip},
+// match any single segment and are passed as path parameters
+type Route struct {
+	Method  string
+	Path    string
+	Handler service.Handler
+}
+
+type router struct {
+	routes []Route
+}
+
+// make http handler dispatching requests to the lambda handlers of matching routes
+func NewServeMux(routes []Route) http.Handler {
+	return &router{routes: routes}
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pathMatched := false
+	for _, route := range rt.routes {
+		pathParams, ok := matchPath(route.Path, r.URL.Path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if route.Method != r.Method {
+			continue
+		}
+		req, err := ToRequest(r)
+		if err != nil {
+			log.Printf("%v", err)
+			writeError(w, http.StatusBadRequest, "unable to read request body")
+			return
+		}
+		req.RouteKey = route.Method + " " + route.Path
+		req.RequestContext.RouteKey = req.RouteKey
+		req.PathParameters = pathParams
+		resp, err := route.Handler(r.Context(), req)
+		if err != nil {
+			log.Printf("%v", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		WriteResponse(w, resp)
+		return
+	}
+	if pathMatched {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "route not found")
+}
+
+// match request path against route path, returning the values of path parameters
+func matchPath(pattern string, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+	var pathParams map[string]string
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			if pathParams == nil {
+				pathParams = make(map[string]string)
+			}
+			pathParams[segment[1:len(segment)-1]] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return pathParams, true
+}
+
+// convert http request to API Gateway HTTP API event
+func ToRequest(r *http.Request) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+	headers := make(map[string]string, len(r.Header))
+	for key, values := range r.Header {
+		headers[strings.ToLower(key)] = strings.Join(values, ",")
+	}
+	var queryParams map[string]string
+	if query := r.URL.Query(); len(query) > 0 {
+		queryParams = make(map[string]string, len(query))
+		for key, values := range query {
+			queryParams[key] = strings.Join(values, ",")
+		}
+	}
+	req := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              r.Method + " " + r.URL.Path,
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Headers:               headers,
+		QueryStringParameters: queryParams,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:  r.Method + " " + r.URL.Path,
+			Time:      time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch: time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  r.RemoteAddr,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+	// binary payloads are base64 encoded the same way API Gateway does
+	if utf8.Valid(body) {
+		req.Body = string(body)
+	} else {
+		req.Body = base64.StdEncoding.EncodeToString(body)
+		req.IsBase64Encoded = true
+	}
+	return req, nil
+}
+
+// write API Gateway HTTP API response to http response writer
+func WriteResponse(w http.ResponseWriter, resp events.APIGatewayV2HTTPResponse) {
+	for key, value := range resp.Headers {
+		w.Header().Set(key, value)
+	}
+	for key, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			log.Printf("%v", err)
+			writeError(w, http.StatusInternalServerError, "invalid base64 response body")
+			return
+		}
+		body = decoded
+	}
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	respBytes, _ := json.Marshal(models.ErrorResponse{
+		Error: message,
+		Code:  code,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(respBytes)
+}
@@ -0,0 +1,103 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ToRequest_Success(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/mock-data?threshold=1&detailed=true", strings.NewReader(`{"ip":"127.0.0.1"}`))
+	r.Header.Set("Content-Type", "application/json")
+	req, err := ToRequest(r)
+	assert.Nil(t, err)
+	assert.Equal(t, req.RawPath, "/mock-data")
+	assert.Equal(t, req.RequestContext.HTTP.Method, http.MethodPost)
+	assert.Equal(t, req.QueryStringParameters, map[string]string{"threshold": "1", "detailed": "true"})
+	assert.Equal(t, req.Headers["content-type"], "application/json")
+	assert.Equal(t, req.Body, `{"ip":"127.0.0.1"}`)
+	assert.False(t, req.IsBase64Encoded)
+}
+
+func Test_ToRequest_BinaryBody_Success(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/mock-data", strings.NewReader("\xff\xfe"))
+	req, err := ToRequest(r)
+	assert.Nil(t, err)
+	assert.Equal(t, req.Body, "//4=")
+	assert.True(t, req.IsBase64Encoded)
+}
+
+func Test_NewServeMux_Success(t *testing.T) {
+	mux := NewServeMux([]Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/mock-data",
+			Handler: func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+				return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK, Body: req.QueryStringParameters["name"]}, nil
+			},
+		},
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mock-data?name=dummy", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "dummy")
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+}
+
+func Test_NewServeMux_MethodNotAllowed_Fail(t *testing.T) {
+	mux := NewServeMux([]Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/mock-data",
+			Handler: func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+				return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK}, nil
+			},
+		},
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/mock-data", nil))
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+}
+
+func Test_NewServeMux_NotFound_Fail(t *testing.T) {
+	mux := NewServeMux(nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dummy", nil))
+	assert.Equal(t, w.Code, http.StatusNotFound)
+}
+
+func Test_NewServeMux_PathParameters_Success(t *testing.T) {
+	mux := NewServeMux([]Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/ip-configs",
+			Handler: func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+				return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK, Body: "list"}, nil
+			},
+		},
+		{
+			Method: http.MethodDelete,
+			Path:   "/ip-configs/{ip}",
+			Handler: func(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+				return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusOK, Body: req.RouteKey + " " + req.PathParameters["ip"]}, nil
+			},
+		},
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/ip-configs/2001:db8::1", nil))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "DELETE /ip-configs/{ip} 2001:db8::1")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ip-configs", nil))
+	assert.Equal(t, w.Body.String(), "list")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ip-configs/127.0.0.1", nil))
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+}
@@ -1,16 +1,222 @@
 package models
 
+import (
+	"time"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+)
+
 type IpConfig struct {
 	Ip       string `json:"ip"`
 	Hostname string `json:"hostname"`
 	Active   bool   `json:"active"`
 }
 
+// changes to a record, fields which are not set are left unchanged
+type IpConfigPatch struct {
+	Hostname *string `json:"hostname"`
+	Active   *bool   `json:"active"`
+}
+
+// metadata of a host, kept separately from its IP configuration records
+type Host struct {
+	Hostname   string   `json:"hostname"`
+	Datacenter string   `json:"datacenter,omitempty"`
+	Pool       string   `json:"pool,omitempty"`
+	Capacity   int      `json:"capacity,omitempty"` // maximum number of active MTAs
+	Tags       []string `json:"tags,omitempty"`
+}
+
+type HostList struct {
+	Hosts      []Host `json:"hosts"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type IpConfigList struct {
+	IpConfigs  []IpConfig `json:"ipConfigs"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// page of server data, records hold only the requested fields if a projection was requested
+type MockDataPage struct {
+	IpConfigs  interface{} `json:"ipConfigs"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
 type ServerResponse struct {
-	Hostnames []string `json:"hostnames"`
+	Hostnames  []string            `json:"hostnames"`
+	Threshold  *int                `json:"threshold,omitempty"` // maximum active MTAs, if part of the rule
+	Rule       Rule                `json:"rule"`
+	Policies   map[string]string   `json:"policies,omitempty"` // policy each returned host was evaluated against, if policies are defined
+	Groups     map[string][]string `json:"groups,omitempty"`   // returned hostnames by datacenter or pool, if grouping was requested
+	Servers    []ServerDetail      `json:"servers,omitempty"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+// ways of combining the criteria of a rule
+const (
+	MatchAll = "and"
+	MatchAny = "or"
+)
+
+// criteria marking a server as inefficient, unset criteria are not evaluated
+type Rule struct {
+	MaxActive      *int     `json:"maxActive,omitempty"`      // active MTAs less than or equal to
+	MaxActiveRatio *float64 `json:"maxActiveRatio,omitempty"` // share of active MTAs less than or equal to
+	MinTotalIps    *int     `json:"minTotalIps,omitempty"`    // total IPs greater than or equal to
+	Match          string   `json:"match"`                    // MatchAll or MatchAny of the criteria
+}
+
+// name of the policy applying the request or configured rule to hosts matching no other policy
+const DefaultPolicy = "default"
+
+// rule applying to a group of hosts, selected by hostname pattern and tags
+type Policy struct {
+	Name     string   `json:"name"`
+	Hostname string   `json:"hostname,omitempty"` // glob pattern, e.g. mta-marketing-*
+	Tags     []string `json:"tags,omitempty"`     // host must have all tags
+	Rule     Rule     `json:"rule"`
+}
+
+// MTA counts of a single host kept in the aggregate index of server data
+type HostCount struct {
+	Hostname     string `json:"hostname"`
+	ActiveMTAs   int    `json:"activeMTAs"`
+	InactiveMTAs int    `json:"inactiveMTAs"`
+}
+
+// MTA usage of a single host, returned in detailed response mode
+type ServerDetail struct {
+	Hostname     string   `json:"hostname"`
+	ActiveMTAs   int      `json:"activeMTAs"`
+	InactiveMTAs int      `json:"inactiveMTAs"`
+	TotalIps     int      `json:"totalIps"`
+	Ips          []string `json:"ips"`
+	Policy       string   `json:"policy,omitempty"`
+	Datacenter   string   `json:"datacenter,omitempty"`
+	Pool         string   `json:"pool,omitempty"`
+	Capacity     int      `json:"capacity,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// plan to move active MTAs off under-utilised hosts
+type ConsolidationPlan struct {
+	Threshold           *int     `json:"threshold,omitempty"` // maximum active MTAs, if part of the rule
+	Rule                Rule     `json:"rule"`                // rule selecting the hosts to empty, as for inefficient servers
+	Capacity            int      `json:"capacity"`
+	DecommissionCount   int      `json:"decommissionCount"`
+	DecommissionedHosts []string `json:"decommissionedHosts"`
+	UnresolvedHosts     []string `json:"unresolvedHosts"` // under-utilised hosts that could not be emptied
+	Moves               []Move   `json:"moves"`
+}
+
+type Move struct {
+	Ip   string `json:"ip"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// active and inactive MTAs per subnet, subnets with an active ratio below the threshold are flagged
+type SubnetReport struct {
+	Ratio          float64        `json:"ratio"`
+	PrefixV4       int            `json:"prefixV4"`
+	PrefixV6       int            `json:"prefixV6"`
+	Subnets        []SubnetDetail `json:"subnets"`
+	FlaggedSubnets []string       `json:"flaggedSubnets"`
+	UnparsedIps    []string       `json:"unparsedIps,omitempty"` // IPs which are not valid addresses and belong to no subnet
+}
+
+type SubnetDetail struct {
+	Subnet       string  `json:"subnet"`
+	ActiveMTAs   int     `json:"activeMTAs"`
+	InactiveMTAs int     `json:"inactiveMTAs"`
+	TotalIps     int     `json:"totalIps"`
+	ActiveRatio  float64 `json:"activeRatio"`
+	Flagged      bool    `json:"flagged"`
+}
+
+// copy of the server data saved at a point in time
+type Snapshot struct {
+	Id      string    `json:"id"`
+	TakenAt time.Time `json:"takenAt"`
+}
+
+// MTA usage of every host over the evaluated snapshots
+type TrendResponse struct {
+	Rule        Rule        `json:"rule"`
+	Consecutive int         `json:"consecutive,omitempty"` // only hosts inefficient in at least this many latest snapshots
+	Snapshots   []Snapshot  `json:"snapshots"`             // evaluated snapshots, oldest first
+	Hosts       []HostTrend `json:"hosts"`
+	NextCursor  string      `json:"nextCursor,omitempty"`
+}
+
+type HostTrend struct {
+	Hostname          string       `json:"hostname"`
+	InefficientStreak int          `json:"inefficientStreak"` // number of latest consecutive snapshots in which the host was inefficient
+	Points            []TrendPoint `json:"points"`            // one point per snapshot holding the host, oldest first
+}
+
+type TrendPoint struct {
+	SnapshotId  string    `json:"snapshotId"`
+	TakenAt     time.Time `json:"takenAt"`
+	ActiveMTAs  int       `json:"activeMTAs"`
+	TotalIps    int       `json:"totalIps"`
+	Inefficient bool      `json:"inefficient"`
+}
+
+// changes of server data between two snapshots
+type SnapshotDiff struct {
+	From                Snapshot       `json:"from"`
+	To                  Snapshot       `json:"to"`
+	Rule                Rule           `json:"rule"`
+	AddedIps            []IpConfig     `json:"addedIps"`
+	RemovedIps          []IpConfig     `json:"removedIps"`
+	Reassigned          []Reassignment `json:"reassigned"`        // IPs moved to another hostname
+	ActiveChanged       []ActiveChange `json:"activeChanged"`     // IPs whose active state flipped
+	BecameInefficient   []string       `json:"becameInefficient"` // hosts inefficient as per rule in to but not in from
+	NoLongerInefficient []string       `json:"noLongerInefficient"`
+}
+
+type Reassignment struct {
+	Ip   string `json:"ip"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ActiveChange struct {
+	Ip       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Active   bool   `json:"active"` // state in to
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
-	Code  int    `json:"statusCode"`
+	Error   string            `json:"error"`
+	Code    int               `json:"statusCode"`
+	Details []errorlib.Detail `json:"details,omitempty"`
+}
+
+type IngestResponse struct {
+	Message    string         `json:"message"`
+	Records    int            `json:"records"`
+	Created    int            `json:"created"`
+	Updated    int            `json:"updated"`
+	Results    []RecordResult `json:"results"`
+	Duplicates []string       `json:"duplicates,omitempty"` // IPs stored more than once before the request
+}
+
+// outcome of writing records keyed by IP
+type UpsertResult struct {
+	Results    []RecordResult
+	Duplicates []string
+}
+
+// record statuses of an upsert
+const (
+	StatusCreated = "created"
+	StatusUpdated = "updated"
+)
+
+type RecordResult struct {
+	Ip     string `json:"ip"`
+	Status string `json:"status"`
 }
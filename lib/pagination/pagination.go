@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+)
+
+const MaxLimit = 1000
+
+// page size of a cursor request without limit
+const DefaultLimit = 100
+
+// page of results requested by the client. Limit 0 means all remaining results
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// content of the opaque cursor handed out to clients
+type cursor struct {
+	Offset int `json:"offset"`
+}
+
+// parse limit and cursor query parameter values. Without both all results are returned, a cursor
+// without limit returns DefaultLimit results
+func Parse(limit string, token string) (Page, errorlib.Error) {
+	var page Page
+	if token != "" {
+		page.Limit = DefaultLimit
+	}
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > MaxLimit {
+			return Page{}, errorlib.New(errors.New("invalid limit query parameter. Please provide an integer between 1 and "+strconv.Itoa(MaxLimit)), http.StatusBadRequest)
+		}
+		page.Limit = value
+	}
+	if token != "" {
+		offset, err := DecodeCursor(token)
+		if err != nil {
+			return Page{}, errorlib.New(errors.New("invalid cursor query parameter"), http.StatusBadRequest)
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+// return slice bounds of the page for a result set of given size and the cursor of the next page
+func (p Page) Bounds(total int) (int, int, string) {
+	start := p.Offset
+	if start > total {
+		start = total
+	}
+	if p.Limit == 0 || start+p.Limit >= total {
+		return start, total, ""
+	}
+	end := start + p.Limit
+	return start, end, EncodeCursor(end)
+}
+
+// encode offset as opaque cursor
+func EncodeCursor(offset int) string {
+	cursorBytes, _ := json.Marshal(cursor{Offset: offset})
+	return base64.RawURLEncoding.EncodeToString(cursorBytes)
+}
+
+// decode offset from opaque cursor
+func DecodeCursor(token string) (int, error) {
+	cursorBytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	var c cursor
+	if err := json.Unmarshal(cursorBytes, &c); err != nil {
+		return 0, err
+	}
+	if c.Offset < 0 {
+		return 0, errors.New("negative cursor offset")
+	}
+	return c.Offset, nil
+}
@@ -0,0 +1,61 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Parse_Success(t *testing.T) {
+	page, err := Parse("10", EncodeCursor(20))
+	assert.Nil(t, err)
+	assert.Equal(t, page, Page{Offset: 20, Limit: 10})
+}
+
+func Test_Parse_Empty_Success(t *testing.T) {
+	page, err := Parse("", "")
+	assert.Nil(t, err)
+	assert.Equal(t, page, Page{})
+}
+
+func Test_Parse_CursorWithoutLimit_Success(t *testing.T) {
+	page, err := Parse("", EncodeCursor(5))
+	assert.Nil(t, err)
+	assert.Equal(t, page, Page{Offset: 5, Limit: DefaultLimit})
+}
+
+func Test_Parse_InvalidLimit_Fail(t *testing.T) {
+	for _, limit := range []string{"dummy", "0", "-1", "1001"} {
+		_, err := Parse(limit, "")
+		assert.Equal(t, err.StatusCode(), 400)
+	}
+}
+
+func Test_Parse_InvalidCursor_Fail(t *testing.T) {
+	_, err := Parse("", "dummy")
+	assert.Equal(t, err.Error(), "invalid cursor query parameter")
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_Bounds_Success(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  Page
+		start int
+		end   int
+		next  string
+	}{
+		{name: "No limit", page: Page{}, start: 0, end: 5, next: ""},
+		{name: "First page", page: Page{Limit: 2}, start: 0, end: 2, next: EncodeCursor(2)},
+		{name: "Last page", page: Page{Offset: 4, Limit: 2}, start: 4, end: 5, next: ""},
+		{name: "Offset past end", page: Page{Offset: 10, Limit: 2}, start: 5, end: 5, next: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, next := tt.page.Bounds(5)
+			assert.Equal(t, start, tt.start)
+			assert.Equal(t, end, tt.end)
+			assert.Equal(t, next, tt.next)
+		})
+	}
+}
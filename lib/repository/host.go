@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+// storage of host metadata
+type HostRepository interface {
+	// get all hosts, none if no metadata is stored
+	Load() ([]models.Host, errorlib.Error)
+	// atomically replace all hosts with the result of modify. Errors returned by modify
+	// abort the update, service errors keep their status code
+	Update(modify func([]models.Host) ([]models.Host, error)) errorlib.Error
+}
+
+// repository keeping all hosts as a JSON array in a single object
+type hostRepository struct {
+	store storage.Store
+	key   string
+}
+
+func NewHostRepository(store storage.Store, key string) HostRepository {
+	return &hostRepository{store: store, key: key}
+}
+
+func (r *hostRepository) Load() ([]models.Host, errorlib.Error) {
+	hostBytes, err := r.store.Get(r.key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return []models.Host{}, nil
+		}
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	hosts := []models.Host{}
+	if err := json.Unmarshal(hostBytes, &hosts); err != nil {
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	return hosts, nil
+}
+
+// read, modify and write all hosts without losing concurrent writes.
+// If object does not exist yet, a new one is created
+func (r *hostRepository) Update(modify func([]models.Host) ([]models.Host, error)) errorlib.Error {
+	err := r.store.Update(r.key, func(data []byte, exists bool) ([]byte, error) {
+		hosts := []models.Host{}
+		if exists {
+			if err := json.Unmarshal(data, &hosts); err != nil {
+				return nil, err
+			}
+		}
+		hosts, err := modify(hosts)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(hosts)
+	})
+	if err != nil {
+		log.Printf("%v", err)
+		return toError(err)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+// storage of the aggregate index of server data, holding the MTA counts of every host and the
+// version of every data object they were counted from. Every change of the index increases its generation
+type IndexRepository interface {
+	// get counts of every host sorted by hostname. Returns 404 if the index was not built or is stale
+	Load() ([]models.HostCount, errorlib.Error)
+	// atomically add changes of host counts made by writes of data objects, dropping hosts left
+	// without MTAs. Changes are only added if the index holds the versions the objects were written
+	// from, and dropped if it already holds the written versions. Otherwise, e.g. if the index missed
+	// a write, the index is marked stale. A missing index is created stale, as changes alone miss the
+	// counts of existing data. Changes of a stale index are dropped
+	Apply(changes []models.HostCount, writes []ObjectWrite) errorlib.Error
+	// mark the index stale, e.g. after a write whose changes could not be applied. A stale index
+	// is not read until it is rebuilt
+	Invalidate() errorlib.Error
+	// get the generation of the index, 0 if the index does not exist
+	Generation() (int64, errorlib.Error)
+	// replace counts of all hosts computed from server data read after getting generation, with the
+	// versions of the data objects read. Returns 409 if the index changed since, as the counts may
+	// miss records written in between
+	Rebuild(counts []models.HostCount, versions map[string]string, generation int64) errorlib.Error
+}
+
+// write of a data object, as the versions of the object before and after the write. The version
+// of a missing object is empty
+type ObjectWrite struct {
+	Key    string
+	Before string
+	After  string
+}
+
+// version of data object content, empty if the object does not exist
+func objectVersion(data []byte, exists bool) string {
+	if !exists {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// repository keeping the counts of all hosts in a single JSON object
+type indexRepository struct {
+	store storage.Store
+	key   string
+}
+
+// stored index, counts of a stale index are dropped. Versions holds the version of every data
+// object counted, by key
+type indexData struct {
+	Generation int64              `json:"generation"`
+	Stale      bool               `json:"stale"`
+	Versions   map[string]string  `json:"versions,omitempty"`
+	Hosts      []models.HostCount `json:"hosts"`
+}
+
+var (
+	errIndexNotFound = errors.New("server index not found")
+	errIndexStale    = errors.New("server index is stale, rebuild the index")
+	errIndexChanged  = errorlib.New(errors.New("server data was written during the rebuild. Please rebuild the index again"), http.StatusConflict)
+)
+
+func NewIndexRepository(store storage.Store, key string) IndexRepository {
+	return &indexRepository{store: store, key: key}
+}
+
+func (r *indexRepository) Load() ([]models.HostCount, errorlib.Error) {
+	index, exists, err := r.get()
+	if err != nil {
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	if !exists {
+		return nil, errorlib.New(errIndexNotFound, http.StatusNotFound)
+	}
+	if index.Stale {
+		return nil, errorlib.New(errIndexStale, http.StatusNotFound)
+	}
+	return addCounts(nil, index.Hosts), nil
+}
+
+func (r *indexRepository) Apply(changes []models.HostCount, writes []ObjectWrite) errorlib.Error {
+	return r.update(func(index indexData, exists bool) (indexData, error) {
+		if !exists || index.Stale {
+			return indexData{Stale: true}, nil
+		}
+		switch {
+		case index.holds(writes, func(write ObjectWrite) string { return write.Before }):
+			for _, write := range writes {
+				index.setVersion(write.Key, write.After)
+			}
+			return indexData{Versions: index.Versions, Hosts: addCounts(index.Hosts, changes)}, nil
+		case index.holds(writes, func(write ObjectWrite) string { return write.After }):
+			// written data was already counted by a rebuild
+			return index, nil
+		default:
+			log.Printf("index %s missed writes of server data and is stale, rebuild the index", r.key)
+			return indexData{Stale: true}, nil
+		}
+	})
+}
+
+// check if the index holds the version of every written object returned by version
+func (index indexData) holds(writes []ObjectWrite, version func(ObjectWrite) string) bool {
+	for _, write := range writes {
+		if index.Versions[write.Key] != version(write) {
+			return false
+		}
+	}
+	return true
+}
+
+// set version of the object at key, a missing object is removed
+func (index *indexData) setVersion(key string, version string) {
+	if index.Versions == nil {
+		index.Versions = make(map[string]string)
+	}
+	if version == "" {
+		delete(index.Versions, key)
+		return
+	}
+	index.Versions[key] = version
+}
+
+func (r *indexRepository) Invalidate() errorlib.Error {
+	return r.update(func(indexData, bool) (indexData, error) {
+		return indexData{Stale: true}, nil
+	})
+}
+
+func (r *indexRepository) Generation() (int64, errorlib.Error) {
+	index, _, err := r.get()
+	if err != nil {
+		log.Printf("%v", err)
+		return 0, errorlib.New(err, http.StatusInternalServerError)
+	}
+	return index.Generation, nil
+}
+
+func (r *indexRepository) Rebuild(counts []models.HostCount, versions map[string]string, generation int64) errorlib.Error {
+	return r.update(func(index indexData, _ bool) (indexData, error) {
+		if index.Generation != generation {
+			return indexData{}, errIndexChanged
+		}
+		return indexData{Versions: versions, Hosts: addCounts(nil, counts)}, nil
+	})
+}
+
+// get the stored index, reporting whether it exists
+func (r *indexRepository) get() (indexData, bool, error) {
+	var index indexData
+	data, err := r.store.Get(r.key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return index, false, nil
+		}
+		return index, false, err
+	}
+	return index, true, json.Unmarshal(data, &index)
+}
+
+// atomically replace the index with the result of modify, increasing its generation
+func (r *indexRepository) update(modify func(index indexData, exists bool) (indexData, error)) errorlib.Error {
+	err := r.store.Update(r.key, func(data []byte, exists bool) ([]byte, error) {
+		var index indexData
+		if exists {
+			if err := json.Unmarshal(data, &index); err != nil {
+				return nil, err
+			}
+		}
+		next, err := modify(index, exists)
+		if err != nil {
+			return nil, err
+		}
+		next.Generation = index.Generation + 1
+		return json.Marshal(next)
+	})
+	if err != nil {
+		if !errors.Is(err, errIndexChanged) {
+			log.Printf("%v", err)
+		}
+		return toError(err)
+	}
+	return nil
+}
+
+// add changes to counts, returning nonzero counts sorted by hostname
+func addCounts(counts []models.HostCount, changes []models.HostCount) []models.HostCount {
+	byHost := make(map[string]*models.HostCount, len(counts))
+	for _, list := range [][]models.HostCount{counts, changes} {
+		for _, change := range list {
+			count, ok := byHost[change.Hostname]
+			if !ok {
+				count = &models.HostCount{Hostname: change.Hostname}
+				byHost[change.Hostname] = count
+			}
+			count.ActiveMTAs += change.ActiveMTAs
+			count.InactiveMTAs += change.InactiveMTAs
+		}
+	}
+	result := []models.HostCount{}
+	for _, count := range byHost {
+		if count.ActiveMTAs != 0 || count.InactiveMTAs != 0 {
+			result = append(result, *count)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Hostname < result[j].Hostname
+	})
+	return result
+}
+
+// changes of host counts from replacing records before with records after
+func countChanges(before []models.IpConfig, after []models.IpConfig) []models.HostCount {
+	var changes []models.HostCount
+	for _, record := range after {
+		changes = append(changes, countOf(record, 1))
+	}
+	for _, record := range before {
+		changes = append(changes, countOf(record, -1))
+	}
+	return addCounts(nil, changes)
+}
+
+func countOf(record models.IpConfig, n int) models.HostCount {
+	if record.Active {
+		return models.HostCount{Hostname: record.Hostname, ActiveMTAs: n}
+	}
+	return models.HostCount{Hostname: record.Hostname, InactiveMTAs: n}
+}
+
+// apply changes of written records to the index, if the index is enabled. If the data was created
+// the index is marked stale instead, as it may hold counts of other data, e.g. of the data before
+// sharding was enabled. A failed update marks the index stale to be rebuilt and does not fail the write
+func (o options) index(key string, before []models.IpConfig, after []models.IpConfig, writes []ObjectWrite, created bool) {
+	if o.indexRepo == nil || len(writes) == 0 {
+		return
+	}
+	if created {
+		o.invalidate(key, errors.New("server data was created"))
+		return
+	}
+	if svcErr := o.indexRepo.Apply(countChanges(before, after), writes); svcErr != nil {
+		o.invalidate(key, svcErr)
+	}
+}
+
+// mark the index stale after all records were replaced, if the index is enabled. Overwriting the
+// counts could drop changes of concurrent writes
+func (o options) reindex(key string) {
+	if o.indexRepo == nil {
+		return
+	}
+	o.invalidate(key, errors.New("all records were replaced"))
+}
+
+// mark the index stale, so that readers fall back to the server data until it is rebuilt
+func (o options) invalidate(key string, reason error) {
+	log.Printf("index of %s is stale, rebuild the index: %v", key, reason)
+	if svcErr := o.indexRepo.Invalidate(); svcErr != nil {
+		log.Printf("index of %s could not be marked stale and may be wrong: %v", key, svcErr)
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+// storage of threshold policies of host groups
+type PolicyRepository interface {
+	// get all policies in order of evaluation, none if no policies are defined
+	Load() ([]models.Policy, errorlib.Error)
+	// replace all policies
+	Save(policies []models.Policy) errorlib.Error
+}
+
+// repository keeping all policies as a JSON array in a single object
+type policyRepository struct {
+	store storage.Store
+	key   string
+}
+
+func NewPolicyRepository(store storage.Store, key string) PolicyRepository {
+	return &policyRepository{store: store, key: key}
+}
+
+func (r *policyRepository) Load() ([]models.Policy, errorlib.Error) {
+	policyBytes, err := r.store.Get(r.key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return []models.Policy{}, nil
+		}
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	policies := []models.Policy{}
+	if err := json.Unmarshal(policyBytes, &policies); err != nil {
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	return policies, nil
+}
+
+func (r *policyRepository) Save(policies []models.Policy) errorlib.Error {
+	policyBytes, err := json.Marshal(policies)
+	if err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	if err := r.store.Put(r.key, policyBytes); err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mta-hosting-optimizer/lib/analyzer"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// storage of server IP configuration records
+type IpConfigRepository interface {
+	// get all records
+	Load() ([]models.IpConfig, errorlib.Error)
+	// read every object holding records as a JSON array without loading it into memory, passing
+	// read the key of the object. Objects may be read concurrently, read must be safe for concurrent
+	// use. Errors returned by read abort the stream, service errors keep their status code
+	Stream(read func(key string, r io.Reader) error) errorlib.Error
+	// replace all records
+	Save(ipConfig []models.IpConfig) errorlib.Error
+	// add records to the existing ones
+	Append(ipConfig ...models.IpConfig) errorlib.Error
+	// create records, or update hostname and active state of records with the same IP.
+	// Records duplicated by IP in existing data are merged, the last one wins
+	Upsert(ipConfig ...models.IpConfig) (models.UpsertResult, errorlib.Error)
+	// atomically replace all records with the result of modify. Errors returned by modify
+	// abort the update, service errors keep their status code
+	Update(modify func([]models.IpConfig) ([]models.IpConfig, error)) errorlib.Error
+}
+
+// get MTA usage of every server, streaming records from the repository. Objects of the records,
+// e.g. shards, are aggregated concurrently and merged. IPs are only collected if withIps is set
+func AggregateServers(repo IpConfigRepository, withIps bool) (map[string]*models.ServerDetail, errorlib.Error) {
+	servers, _, svcErr := aggregateServers(repo, withIps)
+	return servers, svcErr
+}
+
+// AggregateServers also returning the version of every object read, by key
+func aggregateServers(repo IpConfigRepository, withIps bool) (map[string]*models.ServerDetail, map[string]string, errorlib.Error) {
+	servers := make(map[string]*models.ServerDetail)
+	versions := make(map[string]string)
+	var mu sync.Mutex
+	svcErr := repo.Stream(func(key string, r io.Reader) error {
+		h := sha256.New()
+		objectServers, err := analyzer.Aggregate(io.TeeReader(r, h), withIps)
+		if err != nil {
+			return err
+		}
+		// trailing bytes after the records are part of the version
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		analyzer.Merge(servers, objectServers)
+		versions[key] = fmt.Sprintf("%x", h.Sum(nil))
+		return nil
+	})
+	if svcErr != nil {
+		return nil, nil, svcErr
+	}
+	return servers, versions, nil
+}
+
+// regenerate the index from all records of the repository, returning the number of hosts. The
+// index is only replaced if no records were written while they were read
+func RebuildIndex(repo IpConfigRepository, index IndexRepository) (int, errorlib.Error) {
+	generation, svcErr := index.Generation()
+	if svcErr != nil {
+		return 0, svcErr
+	}
+	servers, versions, svcErr := aggregateServers(repo, false)
+	if svcErr != nil {
+		return 0, svcErr
+	}
+	counts := make([]models.HostCount, 0, len(servers))
+	for _, server := range servers {
+		counts = append(counts, models.HostCount{Hostname: server.Hostname, ActiveMTAs: server.ActiveMTAs, InactiveMTAs: server.InactiveMTAs})
+	}
+	if svcErr := index.Rebuild(counts, versions, generation); svcErr != nil {
+		return 0, svcErr
+	}
+	return len(counts), nil
+}
+
+// repository keeping all records as a JSON array in a single object
+type ipConfigRepository struct {
+	options
+	store storage.Store
+	key   string
+}
+
+// optional behaviour of an IP configuration repository
+type Option func(*options)
+
+type options struct {
+	snapshots   SnapshotRepository
+	indexRepo   IndexRepository
+	shards      int
+	concurrency int
+}
+
+// keep a snapshot of the records after every write. A failed snapshot does not fail the write
+func WithSnapshots(snapshots SnapshotRepository) Option {
+	return func(o *options) {
+		o.snapshots = snapshots
+	}
+}
+
+// keep the per host counts of the records in the index up to date on every write. A failed update
+// of the index does not fail the write
+func WithIndex(index IndexRepository) Option {
+	return func(o *options) {
+		o.indexRepo = index
+	}
+}
+
+// spread records across shards, reading or writing at most concurrency shards at a time.
+// Zero shards keep all records in a single object
+func WithShards(shards int, concurrency int) Option {
+	return func(o *options) {
+		o.shards = shards
+		o.concurrency = concurrency
+	}
+}
+
+// time snapshots are taken at, replaced in tests
+var now = time.Now
+
+func NewIpConfigRepository(store storage.Store, key string, opts ...Option) IpConfigRepository {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.shards > 0 {
+		return newShardedIpConfigRepository(store, key, o)
+	}
+	return &ipConfigRepository{options: o, store: store, key: key}
+}
+
+func (r *ipConfigRepository) Load() ([]models.IpConfig, errorlib.Error) {
+	ipConfig, err := r.load()
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errorlib.New(errors.New("server information not found"), http.StatusNotFound)
+		}
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	return ipConfig, nil
+}
+
+func (r *ipConfigRepository) Stream(read func(key string, r io.Reader) error) errorlib.Error {
+	reader, err := r.store.Open(r.key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return errorlib.New(errors.New("server information not found"), http.StatusNotFound)
+		}
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	defer reader.Close()
+	if err := read(r.key, reader); err != nil {
+		log.Printf("%v", err)
+		return toError(err)
+	}
+	return nil
+}
+
+func (r *ipConfigRepository) Save(ipConfig []models.IpConfig) errorlib.Error {
+	ipConfigBytes, err := json.Marshal(ipConfig)
+	if err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	if err := r.store.Put(r.key, ipConfigBytes); err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	r.reindex(r.key)
+	r.snapshot(r.key, ipConfig)
+	return nil
+}
+
+func (r *ipConfigRepository) Append(ipConfig ...models.IpConfig) errorlib.Error {
+	return r.Update(func(existingInfo []models.IpConfig) ([]models.IpConfig, error) {
+		return append(existingInfo, ipConfig...), nil
+	})
+}
+
+func (r *ipConfigRepository) Upsert(ipConfig ...models.IpConfig) (models.UpsertResult, errorlib.Error) {
+	var result models.UpsertResult
+	svcErr := r.Update(func(existingInfo []models.IpConfig) ([]models.IpConfig, error) {
+		// recomputed on every attempt as the existing data may have changed
+		var merged []models.IpConfig
+		merged, result = upsertRecords(existingInfo, ipConfig)
+		return merged, nil
+	})
+	if svcErr != nil {
+		return models.UpsertResult{}, svcErr
+	}
+	if len(result.Duplicates) > 0 {
+		log.Printf("merged duplicated IPs %v", result.Duplicates)
+	}
+	return result, nil
+}
+
+// merge records into existing records using the canonical form of their IP as key, keeping the order of first appearance
+func upsertRecords(existingInfo []models.IpConfig, ipConfig []models.IpConfig) ([]models.IpConfig, models.UpsertResult) {
+	var result models.UpsertResult
+	merged := make([]models.IpConfig, 0, len(existingInfo)+len(ipConfig))
+	index := make(map[string]int, len(existingInfo)+len(ipConfig))
+	duplicates := make(map[string]bool)
+	for _, record := range existingInfo {
+		// records stored before IPs were canonicalized are keyed and rewritten in canonical form
+		record.Ip = validator.NormalizeIp(record.Ip)
+		if i, ok := index[record.Ip]; ok {
+			if !duplicates[record.Ip] {
+				duplicates[record.Ip] = true
+				result.Duplicates = append(result.Duplicates, record.Ip)
+			}
+			merged[i] = record
+			continue
+		}
+		index[record.Ip] = len(merged)
+		merged = append(merged, record)
+	}
+	for _, record := range ipConfig {
+		record.Ip = validator.NormalizeIp(record.Ip)
+		if i, ok := index[record.Ip]; ok {
+			merged[i] = record
+			result.Results = append(result.Results, models.RecordResult{Ip: record.Ip, Status: models.StatusUpdated})
+			continue
+		}
+		index[record.Ip] = len(merged)
+		merged = append(merged, record)
+		result.Results = append(result.Results, models.RecordResult{Ip: record.Ip, Status: models.StatusCreated})
+	}
+	return merged, result
+}
+
+// read, modify and write all records without losing concurrent writes.
+// If object does not exist yet, a new one is created
+func (r *ipConfigRepository) Update(modify func([]models.IpConfig) ([]models.IpConfig, error)) errorlib.Error {
+	var existing, written []models.IpConfig
+	var write ObjectWrite
+	created := false
+	err := r.store.Update(r.key, func(data []byte, exists bool) ([]byte, error) {
+		var existingInfo []models.IpConfig
+		if exists {
+			if err := json.Unmarshal(data, &existingInfo); err != nil {
+				return nil, err
+			}
+		}
+		// copied as modify may change records in place
+		existing = append([]models.IpConfig(nil), existingInfo...)
+		created = !exists
+		ipConfig, err := modify(existingInfo)
+		if err != nil {
+			return nil, err
+		}
+		written = ipConfig
+		writtenBytes, err := json.Marshal(ipConfig)
+		write = ObjectWrite{Key: r.key, Before: objectVersion(data, exists), After: objectVersion(writtenBytes, true)}
+		return writtenBytes, err
+	})
+	if err != nil {
+		log.Printf("%v", err)
+		return toError(err)
+	}
+	r.index(r.key, existing, written, []ObjectWrite{write}, created)
+	r.snapshot(r.key, written)
+	return nil
+}
+
+// save written records of the object at key as a new snapshot, if snapshots are enabled
+func (o options) snapshot(key string, ipConfig []models.IpConfig) {
+	if o.snapshots == nil {
+		return
+	}
+	if svcErr := o.snapshots.Save(now(), ipConfig); svcErr != nil {
+		log.Printf("snapshot of %s failed: %v", key, svcErr)
+	}
+}
+
+// keep status of service errors, any other error is an internal error
+func toError(err error) errorlib.Error {
+	var svcErr errorlib.Error
+	if errors.As(err, &svcErr) {
+		return svcErr
+	}
+	return errorlib.New(err, http.StatusInternalServerError)
+}
+
+func (r *ipConfigRepository) load() ([]models.IpConfig, error) {
+	ipConfigBytes, err := r.store.Get(r.key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("%v", err)
+		}
+		return nil, err
+	}
+	var ipConfig []models.IpConfig
+	if err := json.Unmarshal(ipConfigBytes, &ipConfig); err != nil {
+		log.Printf("%v", err)
+		return nil, err
+	}
+	return ipConfig, nil
+}
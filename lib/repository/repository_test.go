@@ -0,0 +1,680 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Load_NotFound_Fail(t *testing.T) {
+	repo := NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	result, err := repo.Load()
+	assert.Nil(t, result)
+	assert.Equal(t, err.Error(), "server information not found")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_Load_InvalidData_Fail(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.Put("ipConfig.json", []byte("Invalid Data"))
+	repo := NewIpConfigRepository(store, "ipConfig.json")
+	result, err := repo.Load()
+	assert.Nil(t, result)
+	assert.Equal(t, err.StatusCode(), 500)
+}
+
+func Test_Stream_NotFound_Fail(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Single object"},
+		{name: "Sharded", opts: []Option{WithShards(4, 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json", tt.opts...)
+			err := repo.Stream(func(string, io.Reader) error {
+				t.Fatal("no data must be read")
+				return nil
+			})
+			assert.Equal(t, err.Error(), "server information not found")
+			assert.Equal(t, err.StatusCode(), 404)
+		})
+	}
+}
+
+func Test_SaveAndAppend_Success(t *testing.T) {
+	repo := NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	err := repo.Append(models.IpConfig{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true})
+	assert.Nil(t, err)
+	err = repo.Append(
+		models.IpConfig{Ip: "DummyIP2", Hostname: "DummyHostname1", Active: false},
+		models.IpConfig{Ip: "DummyIP3", Hostname: "DummyHostname2", Active: true},
+	)
+	assert.Nil(t, err)
+	result, err := repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, result, []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP2", Hostname: "DummyHostname1", Active: false},
+		{Ip: "DummyIP3", Hostname: "DummyHostname2", Active: true},
+	})
+
+	err = repo.Save([]models.IpConfig{{Ip: "DummyIP4", Hostname: "DummyHostname4", Active: true}})
+	assert.Nil(t, err)
+	result, err = repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, result, []models.IpConfig{{Ip: "DummyIP4", Hostname: "DummyHostname4", Active: true}})
+}
+
+func Test_Append_Concurrent_Success(t *testing.T) {
+	repo := NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			repo.Append(models.IpConfig{Ip: "DummyIP" + strconv.Itoa(i), Hostname: "DummyHostname1", Active: true})
+		}(i)
+	}
+	wg.Wait()
+	result, err := repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, len(result), 20)
+}
+
+func Test_Upsert_Success(t *testing.T) {
+	repo := NewIpConfigRepository(storage.NewMemoryStore(), "ipConfig.json")
+	result, err := repo.Upsert(
+		models.IpConfig{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		models.IpConfig{Ip: "DummyIP1", Hostname: "DummyHostname2", Active: false},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, result, models.UpsertResult{
+		Results: []models.RecordResult{
+			{Ip: "DummyIP1", Status: models.StatusCreated},
+			{Ip: "DummyIP1", Status: models.StatusUpdated},
+		},
+	})
+	ipConfig, err := repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, ipConfig, []models.IpConfig{{Ip: "DummyIP1", Hostname: "DummyHostname2", Active: false}})
+}
+
+func Test_upsertRecords_Duplicates_Success(t *testing.T) {
+	existingInfo := []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP2", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP1", Hostname: "DummyHostname2", Active: true},
+		{Ip: "DummyIP1", Hostname: "DummyHostname3", Active: false},
+	}
+	merged, result := upsertRecords(existingInfo, []models.IpConfig{{Ip: "DummyIP3", Hostname: "DummyHostname1", Active: true}})
+	assert.Equal(t, merged, []models.IpConfig{
+		{Ip: "DummyIP1", Hostname: "DummyHostname3", Active: false},
+		{Ip: "DummyIP2", Hostname: "DummyHostname1", Active: true},
+		{Ip: "DummyIP3", Hostname: "DummyHostname1", Active: true},
+	})
+	assert.Equal(t, result.Duplicates, []string{"DummyIP1"})
+	assert.Equal(t, result.Results, []models.RecordResult{{Ip: "DummyIP3", Status: models.StatusCreated}})
+}
+
+func Test_upsertRecords_CanonicalIp_Success(t *testing.T) {
+	existingInfo := []models.IpConfig{
+		{Ip: "2001:DB8::1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "::ffff:10.0.0.1", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.1", Hostname: "DummyHostname2", Active: true},
+	}
+	merged, result := upsertRecords(existingInfo, []models.IpConfig{
+		{Ip: "2001:db8:0:0::1", Hostname: "DummyHostname3", Active: false},
+		{Ip: "::FFFF:10.0.0.2", Hostname: "DummyHostname3", Active: true},
+	})
+	assert.Equal(t, merged, []models.IpConfig{
+		{Ip: "2001:db8::1", Hostname: "DummyHostname3", Active: false},
+		{Ip: "10.0.0.1", Hostname: "DummyHostname2", Active: true},
+		{Ip: "10.0.0.2", Hostname: "DummyHostname3", Active: true},
+	})
+	assert.Equal(t, result.Duplicates, []string{"10.0.0.1"})
+	assert.Equal(t, result.Results, []models.RecordResult{
+		{Ip: "2001:db8::1", Status: models.StatusUpdated},
+		{Ip: "10.0.0.2", Status: models.StatusCreated},
+	})
+}
+
+func Test_PolicyRepository_Success(t *testing.T) {
+	repo := NewPolicyRepository(storage.NewMemoryStore(), "policies.json")
+	policies, err := repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, policies, []models.Policy{})
+
+	maxActive := 2
+	expected := []models.Policy{{Name: "marketing", Hostname: "mta-marketing-*", Rule: models.Rule{MaxActive: &maxActive}}}
+	err = repo.Save(expected)
+	assert.Nil(t, err)
+	policies, err = repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, policies, expected)
+}
+
+func Test_PolicyRepository_InvalidData_Fail(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.Put("policies.json", []byte("Invalid Data"))
+	_, err := NewPolicyRepository(store, "policies.json").Load()
+	assert.Equal(t, err.StatusCode(), 500)
+}
+
+func Test_HostRepository_Success(t *testing.T) {
+	repo := NewHostRepository(storage.NewMemoryStore(), "hosts.json")
+	hosts, err := repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, hosts, []models.Host{})
+
+	expected := []models.Host{{Hostname: "DummyHostname1", Datacenter: "dc1", Pool: "marketing", Capacity: 10, Tags: []string{"warm-up"}}}
+	err = repo.Update(func(hosts []models.Host) ([]models.Host, error) {
+		return append(hosts, expected...), nil
+	})
+	assert.Nil(t, err)
+	hosts, err = repo.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, hosts, expected)
+}
+
+func Test_SnapshotRepository_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	snapshots := NewSnapshotRepository(store, "snapshots/", 0)
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithSnapshots(snapshots))
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		takenAt = takenAt.Add(time.Hour)
+		return takenAt
+	}
+	defer func() { now = time.Now }()
+	first := []models.IpConfig{{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true}}
+	err := repo.Save(first)
+	assert.Nil(t, err)
+	err = repo.Append(models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false})
+	assert.Nil(t, err)
+	// objects which are not snapshots are skipped
+	store.Put("snapshots/README", []byte("Invalid Data"))
+
+	list, err := snapshots.List()
+	assert.Nil(t, err)
+	assert.Equal(t, list, []models.Snapshot{
+		{Id: "20240101T010000.000000000Z", TakenAt: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)},
+		{Id: "20240101T020000.000000000Z", TakenAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)},
+	})
+	ipConfig, err := snapshots.Load(list[0].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, ipConfig, first)
+	ipConfig, err = snapshots.Load(list[1].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, len(ipConfig), 2)
+}
+
+func Test_SnapshotRepository_Retention_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	snapshots := NewSnapshotRepository(store, "snapshots/", 2*time.Hour)
+	ipConfig := []models.IpConfig{{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true}}
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		takenAt = takenAt.Add(time.Hour)
+		err := snapshots.Save(takenAt, ipConfig)
+		assert.Nil(t, err)
+	}
+	// a burst of snapshots does not evict snapshots within retention
+	for i := 0; i < 5; i++ {
+		takenAt = takenAt.Add(time.Second)
+		err := snapshots.Save(takenAt, ipConfig)
+		assert.Nil(t, err)
+	}
+	// only the snapshot older than two hours is deleted
+	list, err := snapshots.List()
+	assert.Nil(t, err)
+	assert.Equal(t, len(list), 7)
+	assert.Equal(t, list[0], models.Snapshot{Id: "20240101T020000.000000000Z", TakenAt: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)})
+	_, err = snapshots.Load("20240101T010000.000000000Z")
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_ShardedRepository_Snapshot_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	snapshots := NewSnapshotRepository(store, "snapshots/", 0)
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithSnapshots(snapshots), WithShards(2, 1))
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		takenAt = takenAt.Add(time.Hour)
+		return takenAt
+	}
+	defer func() { now = time.Now }()
+	// appends and upserts take no snapshot
+	repo.Append(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+	repo.Upsert(models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false})
+	list, _ := snapshots.List()
+	assert.Equal(t, len(list), 0)
+
+	// updates snapshot the written records
+	err := repo.Update(func(existingInfo []models.IpConfig) ([]models.IpConfig, error) {
+		return append(existingInfo, models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true}), nil
+	})
+	assert.Nil(t, err)
+	list, _ = snapshots.List()
+	assert.Equal(t, len(list), 1)
+	ipConfig, _ := snapshots.Load(list[0].Id)
+	expected, _ := repo.Load()
+	assert.Equal(t, ipConfig, expected)
+}
+
+func Test_SnapshotRepository_Load_Fail(t *testing.T) {
+	snapshots := NewSnapshotRepository(storage.NewMemoryStore(), "snapshots/", 0)
+	_, err := snapshots.Load("20240101T010000.000000000Z")
+	assert.Equal(t, err.StatusCode(), 404)
+	_, err = snapshots.Load("../ipConfig")
+	assert.Equal(t, err.StatusCode(), 400)
+}
+
+func Test_ShardedRepository_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithShards(4, 2))
+	var ipConfig []models.IpConfig
+	for i := 0; i < 20; i++ {
+		ipConfig = append(ipConfig, models.IpConfig{Ip: "DummyIP" + strconv.Itoa(i), Hostname: "DummyHostname" + strconv.Itoa(i%3), Active: i%2 == 0})
+	}
+	err := repo.Append(ipConfig[:10]...)
+	assert.Nil(t, err)
+	result, err := repo.Upsert(append(ipConfig[10:], models.IpConfig{Ip: "DummyIP0", Hostname: "DummyHostname9"})...)
+	assert.Nil(t, err)
+	assert.Equal(t, len(result.Results), 11)
+	for i, record := range result.Results[:10] {
+		assert.Equal(t, record, models.RecordResult{Ip: "DummyIP" + strconv.Itoa(i+10), Status: models.StatusCreated})
+	}
+	assert.Equal(t, result.Results[10], models.RecordResult{Ip: "DummyIP0", Status: models.StatusUpdated})
+
+	keys, _ := store.List("ipConfig/")
+	assert.Equal(t, keys, []string{
+		"ipConfig/manifest.json",
+		"ipConfig/shard-0000.json",
+		"ipConfig/shard-0001.json",
+		"ipConfig/shard-0002.json",
+		"ipConfig/shard-0003.json",
+	})
+	ipConfig[0].Hostname = "DummyHostname9"
+	ipConfig[0].Active = false
+	loaded, err := repo.Load()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, loaded, ipConfig)
+
+	var mu sync.Mutex
+	var streamed []models.IpConfig
+	err = repo.Stream(func(_ string, r io.Reader) error {
+		var shard []models.IpConfig
+		if err := json.NewDecoder(r).Decode(&shard); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		streamed = append(streamed, shard...)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, streamed, ipConfig)
+
+	err = repo.Update(func(existingInfo []models.IpConfig) ([]models.IpConfig, error) {
+		return existingInfo[1:], nil
+	})
+	assert.Nil(t, err)
+	loaded, _ = repo.Load()
+	assert.Equal(t, len(loaded), 19)
+}
+
+func Test_ShardedRepository_Save_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	NewIpConfigRepository(store, "ipConfig.json", WithShards(2, 1)).Append(models.IpConfig{Ip: "DummyIP1"})
+	// saving lays records out across the configured shards, replacing the previous layout
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithShards(3, 1))
+	err := repo.Save([]models.IpConfig{{Ip: "DummyIP2"}, {Ip: "DummyIP3"}})
+	assert.Nil(t, err)
+	manifest, _ := store.Get("ipConfig/manifest.json")
+	assert.Equal(t, string(manifest), `{"shards":["ipConfig/shard-0000.json","ipConfig/shard-0001.json","ipConfig/shard-0002.json"]}`)
+	result, err := repo.Load()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, result, []models.IpConfig{{Ip: "DummyIP2"}, {Ip: "DummyIP3"}})
+}
+
+func Test_ShardedRepository_Update_Conflict_Fail(t *testing.T) {
+	store := storage.NewMemoryStore()
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithShards(2, 1))
+	repo.Append(models.IpConfig{Ip: "DummyIP1"})
+	attempts := 0
+	err := repo.Update(func(existingInfo []models.IpConfig) ([]models.IpConfig, error) {
+		attempts++
+		// concurrent write to the shard being updated
+		repo.Append(models.IpConfig{Ip: "DummyIP1", Hostname: "DummyHostname" + strconv.Itoa(attempts)})
+		return nil, nil
+	})
+	assert.Equal(t, err.StatusCode(), 409)
+	assert.Equal(t, attempts, 3)
+}
+
+func Test_ShardedRepository_Update_PartialConflict_Fail(t *testing.T) {
+	store := storage.NewMemoryStore()
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithShards(2, 1))
+	// IPs of the first and the second shard
+	var ips [2][]string
+	for i := 0; len(ips[0]) < 1 || len(ips[1]) < 2; i++ {
+		ip := "DummyIP" + strconv.Itoa(i)
+		ips[shardOf(ip, 2)] = append(ips[shardOf(ip, 2)], ip)
+	}
+	repo.Append(models.IpConfig{Ip: ips[0][0], Hostname: "DummyHostname1"}, models.IpConfig{Ip: ips[1][0], Hostname: "DummyHostname1"})
+	attempts := 0
+	err := repo.Update(func(existingInfo []models.IpConfig) ([]models.IpConfig, error) {
+		attempts++
+		// concurrent write to the second shard only, the first shard is written all the same
+		repo.Append(models.IpConfig{Ip: ips[1][1], Hostname: "DummyHostname3"})
+		for i := range existingInfo {
+			existingInfo[i].Hostname = "DummyHostname2"
+		}
+		return existingInfo, nil
+	})
+	assert.Equal(t, err.StatusCode(), 409)
+	assert.Equal(t, attempts, 1)
+	// the update is not merged into the second shard, which is recorded as needing repair
+	result, _ := repo.Load()
+	assert.ElementsMatch(t, result, []models.IpConfig{
+		{Ip: ips[0][0], Hostname: "DummyHostname2"},
+		{Ip: ips[1][0], Hostname: "DummyHostname1"},
+		{Ip: ips[1][1], Hostname: "DummyHostname3"},
+	})
+	data, _ := store.Get("ipConfig/manifest.json")
+	var m manifest
+	json.Unmarshal(data, &m)
+	assert.Equal(t, m.Repair, []string{"ipConfig/shard-0001.json"})
+
+	// saving all records clears the repair list
+	repo.Save(result)
+	data, _ = store.Get("ipConfig/manifest.json")
+	m = manifest{}
+	json.Unmarshal(data, &m)
+	assert.Nil(t, m.Repair)
+}
+
+func Test_forEach_Success(t *testing.T) {
+	var running, maxRunning int
+	var mu sync.Mutex
+	err := forEach(10, 3, func(i int) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if i >= 5 {
+			return errors.New("failed " + strconv.Itoa(i))
+		}
+		return nil
+	})
+	assert.Equal(t, err.Error(), "failed 5")
+	assert.LessOrEqual(t, maxRunning, 3)
+}
+
+func Test_IndexRepository_Success(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Single object"},
+		{name: "Sharded", opts: []Option{WithShards(4, 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			index := NewIndexRepository(store, "index.json")
+			repo := NewIpConfigRepository(store, "ipConfig.json", append(tt.opts, WithIndex(index))...)
+			_, err := index.Load()
+			assert.Equal(t, err.StatusCode(), 404)
+
+			// creating the data marks the index stale
+			repo.Append(
+				models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true},
+				models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false},
+			)
+			_, err = index.Load()
+			assert.Equal(t, err.Error(), "server index is stale, rebuild the index")
+			hosts, err := RebuildIndex(repo, index)
+			assert.Nil(t, err)
+			assert.Equal(t, hosts, 1)
+
+			repo.Upsert(
+				models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname2", Active: true},
+				models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: false},
+			)
+			counts, err := index.Load()
+			assert.Nil(t, err)
+			assert.Equal(t, counts, []models.HostCount{
+				{Hostname: "DummyHostname1", ActiveMTAs: 1},
+				{Hostname: "DummyHostname2", ActiveMTAs: 1, InactiveMTAs: 1},
+			})
+
+			// records changed in place are counted from their previous state
+			repo.Update(func(ipConfig []models.IpConfig) ([]models.IpConfig, error) {
+				for i := range ipConfig {
+					ipConfig[i].Active = !ipConfig[i].Active
+				}
+				return ipConfig, nil
+			})
+			counts, _ = index.Load()
+			assert.Equal(t, counts, []models.HostCount{
+				{Hostname: "DummyHostname1", InactiveMTAs: 1},
+				{Hostname: "DummyHostname2", ActiveMTAs: 1, InactiveMTAs: 1},
+			})
+
+			// replacing all records marks the index stale
+			repo.Save([]models.IpConfig{{Ip: "10.0.0.4", Hostname: "DummyHostname4", Active: true}})
+			_, err = index.Load()
+			assert.Equal(t, err.StatusCode(), 404)
+			RebuildIndex(repo, index)
+			counts, _ = index.Load()
+			assert.Equal(t, counts, []models.HostCount{{Hostname: "DummyHostname4", ActiveMTAs: 1}})
+		})
+	}
+}
+
+func Test_IndexRepository_Apply_Stale_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	index := NewIndexRepository(store, "index.json")
+	// changes of existing data create a stale index
+	repo := NewIpConfigRepository(store, "ipConfig.json")
+	repo.Append(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+	err := NewIpConfigRepository(store, "ipConfig.json", WithIndex(index)).Append(models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1"})
+	assert.Nil(t, err)
+	_, err = index.Load()
+	assert.Equal(t, err.Error(), "server index is stale, rebuild the index")
+	assert.Equal(t, err.StatusCode(), 404)
+
+	err = index.Apply([]models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1}}, []ObjectWrite{{Key: "ipConfig.json", After: "1"}})
+	assert.Nil(t, err)
+	_, err = index.Load()
+	assert.Equal(t, err.StatusCode(), 404)
+}
+
+func Test_IndexRepository_Apply_Versions_Success(t *testing.T) {
+	index := NewIndexRepository(storage.NewMemoryStore(), "index.json")
+	counts := []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1}}
+	index.Rebuild(counts, map[string]string{"ipConfig.json": "1"}, 0)
+
+	// changes written from the indexed version are added
+	err := index.Apply([]models.HostCount{{Hostname: "DummyHostname1", InactiveMTAs: 1}}, []ObjectWrite{{Key: "ipConfig.json", Before: "1", After: "2"}})
+	assert.Nil(t, err)
+	result, _ := index.Load()
+	assert.Equal(t, result, []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1}})
+
+	// changes of an already indexed version are dropped
+	err = index.Apply([]models.HostCount{{Hostname: "DummyHostname1", InactiveMTAs: 1}}, []ObjectWrite{{Key: "ipConfig.json", Before: "1", After: "2"}})
+	assert.Nil(t, err)
+	result, _ = index.Load()
+	assert.Equal(t, result, []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1}})
+
+	// changes written from a version the index missed mark it stale
+	err = index.Apply([]models.HostCount{{Hostname: "DummyHostname1", InactiveMTAs: 1}}, []ObjectWrite{{Key: "ipConfig.json", Before: "3", After: "4"}})
+	assert.Nil(t, err)
+	_, err = index.Load()
+	assert.Equal(t, err.Error(), "server index is stale, rebuild the index")
+}
+
+func Test_IndexRepository_Rebuild_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	index := NewIndexRepository(store, "index.json")
+	generation, err := index.Generation()
+	assert.Nil(t, err)
+	assert.Equal(t, generation, int64(0))
+	counts := []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1}}
+	err = index.Rebuild(counts, map[string]string{"ipConfig.json": "1"}, generation)
+	assert.Nil(t, err)
+	result, err := index.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, result, counts)
+
+	// changes applied after reading the generation fail the rebuild
+	generation, _ = index.Generation()
+	index.Apply([]models.HostCount{{Hostname: "DummyHostname2", InactiveMTAs: 1}}, []ObjectWrite{{Key: "ipConfig.json", Before: "1", After: "2"}})
+	err = index.Rebuild(counts, map[string]string{"ipConfig.json": "1"}, generation)
+	assert.Equal(t, err.StatusCode(), 409)
+	result, _ = index.Load()
+	assert.Equal(t, result, []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1}, {Hostname: "DummyHostname2", InactiveMTAs: 1}})
+}
+
+// index whose changes cannot be applied
+type failingIndexRepository struct {
+	IndexRepository
+}
+
+func (failingIndexRepository) Apply([]models.HostCount, []ObjectWrite) errorlib.Error {
+	return errorlib.New(errors.New("apply failed"), http.StatusInternalServerError)
+}
+
+func Test_IndexRepository_Invalidate_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	index := NewIndexRepository(store, "index.json")
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithIndex(index))
+	repo.Append(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+	RebuildIndex(repo, index)
+	// a write whose changes are not applied marks the index stale
+	err := NewIpConfigRepository(store, "ipConfig.json", WithIndex(failingIndexRepository{index})).Append(models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1"})
+	assert.Nil(t, err)
+	_, err = index.Load()
+	assert.Equal(t, err.Error(), "server index is stale, rebuild the index")
+	assert.Equal(t, err.StatusCode(), 404)
+
+	// changes of a stale index are dropped until it is rebuilt
+	repo.Append(models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname1"})
+	_, err = index.Load()
+	assert.Equal(t, err.StatusCode(), 404)
+	hosts, err := RebuildIndex(repo, index)
+	assert.Nil(t, err)
+	assert.Equal(t, hosts, 1)
+	counts, err := index.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, counts, []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 2}})
+}
+
+func Test_IndexRepository_Created_Success(t *testing.T) {
+	store := storage.NewMemoryStore()
+	index := NewIndexRepository(store, "index.json")
+	repo := NewIpConfigRepository(store, "ipConfig.json", WithIndex(index))
+	repo.Append(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+	RebuildIndex(repo, index)
+	// first write of data migrated to sharded storage marks the index holding counts of the previous data stale
+	repo = NewIpConfigRepository(store, "ipConfig.json", WithIndex(index), WithShards(2, 1))
+	repo.Upsert(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+	_, err := index.Load()
+	assert.Equal(t, err.StatusCode(), 404)
+	RebuildIndex(repo, index)
+	counts, err := index.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, counts, []models.HostCount{{Hostname: "DummyHostname1", ActiveMTAs: 1}})
+}
+
+// index whose changes are held back until released, as if the write was slow to update the index
+type delayedIndexRepository struct {
+	IndexRepository
+	pending *[]func()
+}
+
+func (r delayedIndexRepository) Apply(changes []models.HostCount, writes []ObjectWrite) errorlib.Error {
+	*r.pending = append(*r.pending, func() { r.IndexRepository.Apply(changes, writes) })
+	return nil
+}
+
+func (r delayedIndexRepository) Invalidate() errorlib.Error {
+	*r.pending = append(*r.pending, func() { r.IndexRepository.Invalidate() })
+	return nil
+}
+
+func (r delayedIndexRepository) release() {
+	for _, apply := range *r.pending {
+		apply()
+	}
+	*r.pending = nil
+}
+
+func Test_IndexRepository_ConcurrentWrites_Success(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Single object"},
+		{name: "Sharded", opts: []Option{WithShards(4, 2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			index := NewIndexRepository(store, "index.json")
+			delayed := delayedIndexRepository{IndexRepository: index, pending: new([]func())}
+			repo := NewIpConfigRepository(store, "ipConfig.json", append(tt.opts, WithIndex(index))...)
+			slowRepo := NewIpConfigRepository(store, "ipConfig.json", append(tt.opts, WithIndex(delayed))...)
+			index.Rebuild(nil, nil, 0)
+
+			// a create whose index update lands after a concurrent update
+			slowRepo.Append(models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+			repo.Upsert(models.IpConfig{Ip: "10.0.0.2", Hostname: "DummyHostname1", Active: false})
+			delayed.release()
+			// the index is stale rather than missing either write
+			_, err := index.Load()
+			assert.Equal(t, err.Error(), "server index is stale, rebuild the index")
+			RebuildIndex(repo, index)
+
+			// an update whose data is read by a rebuild but whose changes land after the rebuild
+			slowRepo.Upsert(models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: true})
+			_, err = RebuildIndex(repo, index)
+			assert.Nil(t, err)
+			delayed.release()
+			// is counted once
+			counts, err := index.Load()
+			assert.Nil(t, err)
+			assert.Equal(t, counts, []models.HostCount{
+				{Hostname: "DummyHostname1", ActiveMTAs: 1, InactiveMTAs: 1},
+				{Hostname: "DummyHostname2", ActiveMTAs: 1},
+			})
+
+			// updates of the same object whose changes land in a different order than their data
+			slowRepo.Upsert(models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname2", Active: false})
+			repo.Upsert(models.IpConfig{Ip: "10.0.0.3", Hostname: "DummyHostname3", Active: true})
+			delayed.release()
+			_, err = index.Load()
+			assert.Equal(t, err.Error(), "server index is stale, rebuild the index")
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+	"github.com/mta-hosting-optimizer/lib/validator"
+)
+
+// repository spreading records across shard objects by IP, so that writes only rewrite the
+// shards holding the written IPs. A manifest object lists the shards. Snapshots are only taken by
+// writes holding all records, Save and Update, as appended or upserted records alone are no copy
+// of the data
+type shardedIpConfigRepository struct {
+	options
+	store storage.Store
+	base  string
+}
+
+// list of shard keys, the position of a key is the shard number. Repair lists the shards an update
+// could not be written to after other shards of the same update were written, until all records are saved again
+type manifest struct {
+	Shards []string `json:"shards"`
+	Repair []string `json:"repair,omitempty"`
+}
+
+// attempts of an update whose shards were changed before any of them was written
+const updateAttempts = 3
+
+var errConflict = errorlib.New(errors.New("data was modified concurrently. Please retry the request"), http.StatusConflict)
+
+var errPartialConflict = errorlib.New(errors.New("data was modified concurrently and the update was only partially written. Please check the data and retry the request"), http.StatusConflict)
+
+func newShardedIpConfigRepository(store storage.Store, key string, o options) *shardedIpConfigRepository {
+	if o.concurrency <= 0 {
+		o.concurrency = 1
+	}
+	return &shardedIpConfigRepository{options: o, store: store, base: strings.TrimSuffix(key, path.Ext(key))}
+}
+
+func (r *shardedIpConfigRepository) manifestKey() string {
+	return r.base + "/manifest.json"
+}
+
+func (r *shardedIpConfigRepository) shardKeys(count int) []string {
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s/shard-%04d.json", r.base, i)
+	}
+	return keys
+}
+
+// shard number of an IP, by its canonical form
+func shardOf(ip string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(validator.NormalizeIp(ip)))
+	return int(h.Sum32() % uint32(count))
+}
+
+// split records by shard, keeping their order within a shard
+func partition(ipConfig []models.IpConfig, count int) [][]models.IpConfig {
+	shards := make([][]models.IpConfig, count)
+	for _, record := range ipConfig {
+		i := shardOf(record.Ip, count)
+		shards[i] = append(shards[i], record)
+	}
+	return shards
+}
+
+// read the manifest. If it does not exist yet and create is set, a manifest with the configured
+// number of shards is created, keeping a manifest written concurrently by another writer. Reports
+// whether the manifest was created
+func (r *shardedIpConfigRepository) manifest(create bool) (manifest, bool, error) {
+	created := false
+	data, err := r.store.Get(r.manifestKey())
+	if errors.Is(err, storage.ErrNotFound) && create {
+		err = r.store.Update(r.manifestKey(), func(current []byte, exists bool) ([]byte, error) {
+			created = !exists
+			if exists {
+				data = current
+				return current, nil
+			}
+			var err error
+			data, err = json.Marshal(manifest{Shards: r.shardKeys(r.shards)})
+			return data, err
+		})
+	}
+	if err != nil {
+		return manifest{}, false, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, false, err
+	}
+	if len(m.Shards) == 0 {
+		return manifest{}, false, errors.New("manifest " + r.manifestKey() + " lists no shards")
+	}
+	return m, created, nil
+}
+
+// run fn for every index below n with at most limit calls running at a time, returning the
+// error of the lowest failed index
+func forEach(n int, limit int, fn func(i int) error) error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get shard data, a shard which was not written yet holds no records
+func (r *shardedIpConfigRepository) getShard(key string) ([]byte, bool, error) {
+	data, err := r.store.Get(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, false, nil
+	}
+	return data, err == nil, err
+}
+
+func decodeShard(data []byte) ([]models.IpConfig, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var ipConfig []models.IpConfig
+	if err := json.Unmarshal(data, &ipConfig); err != nil {
+		return nil, err
+	}
+	return ipConfig, nil
+}
+
+func (r *shardedIpConfigRepository) Load() ([]models.IpConfig, errorlib.Error) {
+	m, _, err := r.manifest(false)
+	if err != nil {
+		return nil, r.readError(err)
+	}
+	shards := make([][]models.IpConfig, len(m.Shards))
+	err = forEach(len(m.Shards), r.concurrency, func(i int) error {
+		data, _, err := r.getShard(m.Shards[i])
+		if err != nil {
+			return err
+		}
+		shards[i], err = decodeShard(data)
+		return err
+	})
+	if err != nil {
+		return nil, r.readError(err)
+	}
+	var ipConfig []models.IpConfig
+	for _, shard := range shards {
+		ipConfig = append(ipConfig, shard...)
+	}
+	return ipConfig, nil
+}
+
+func (r *shardedIpConfigRepository) Stream(read func(key string, r io.Reader) error) errorlib.Error {
+	m, _, err := r.manifest(false)
+	if err != nil {
+		return r.readError(err)
+	}
+	err = forEach(len(m.Shards), r.concurrency, func(i int) error {
+		reader, err := r.store.Open(m.Shards[i])
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		return read(m.Shards[i], reader)
+	})
+	if err != nil {
+		return r.readError(err)
+	}
+	return nil
+}
+
+// report missing manifest as missing data, any other error is logged
+func (r *shardedIpConfigRepository) readError(err error) errorlib.Error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return errorlib.New(errors.New("server information not found"), http.StatusNotFound)
+	}
+	log.Printf("%v", err)
+	return toError(err)
+}
+
+// replace all records, laying them out across the configured number of shards. The new manifest
+// clears the repair list
+func (r *shardedIpConfigRepository) Save(ipConfig []models.IpConfig) errorlib.Error {
+	m := manifest{Shards: r.shardKeys(r.shards)}
+	shards := partition(ipConfig, len(m.Shards))
+	err := forEach(len(m.Shards), r.concurrency, func(i int) error {
+		data, err := json.Marshal(shards[i])
+		if err != nil {
+			return err
+		}
+		return r.store.Put(m.Shards[i], data)
+	})
+	if err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	// written last so that readers never see a manifest listing unwritten shards
+	data, err := json.Marshal(m)
+	if err == nil {
+		err = r.store.Put(r.manifestKey(), data)
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	r.reindex(r.base)
+	r.snapshot(r.base, ipConfig)
+	return nil
+}
+
+// read, modify and write the shards the given records belong to, passing modify the shard number
+// and the shard's records in input order. Returns the number of shards. No snapshot is taken, as
+// that would read every other shard on each write
+func (r *shardedIpConfigRepository) updateShards(ipConfig []models.IpConfig, modify func(shard int, existingInfo []models.IpConfig, ipConfig []models.IpConfig) []models.IpConfig) (int, errorlib.Error) {
+	m, created, err := r.manifest(true)
+	if err != nil {
+		log.Printf("%v", err)
+		return 0, toError(err)
+	}
+	shards := partition(ipConfig, len(m.Shards))
+	changes := make([]shardChange, len(m.Shards))
+	err = forEach(len(m.Shards), r.concurrency, func(i int) error {
+		if len(shards[i]) == 0 {
+			return nil
+		}
+		var change shardChange
+		err := r.store.Update(m.Shards[i], func(data []byte, exists bool) ([]byte, error) {
+			existingInfo, err := decodeShard(data)
+			if err != nil {
+				return nil, err
+			}
+			change.before = append([]models.IpConfig(nil), existingInfo...)
+			change.after = modify(i, existingInfo, shards[i])
+			after, err := json.Marshal(change.after)
+			change.write = ObjectWrite{Key: m.Shards[i], Before: objectVersion(data, exists), After: objectVersion(after, true)}
+			return after, err
+		})
+		if err == nil {
+			changes[i] = change
+		}
+		return err
+	})
+	// shards written before a failure are indexed as well
+	r.indexShards(changes, created)
+	if err != nil {
+		log.Printf("%v", err)
+		return 0, toError(err)
+	}
+	return len(m.Shards), nil
+}
+
+// records of a shard before and after a write, and the versions of the shard. The change of a
+// shard which was not written has no write key
+type shardChange struct {
+	before []models.IpConfig
+	after  []models.IpConfig
+	write  ObjectWrite
+}
+
+// apply changes of written shards to the index
+func (r *shardedIpConfigRepository) indexShards(changes []shardChange, created bool) {
+	var before, after []models.IpConfig
+	var writes []ObjectWrite
+	for _, change := range changes {
+		if change.write.Key == "" {
+			continue
+		}
+		before = append(before, change.before...)
+		after = append(after, change.after...)
+		writes = append(writes, change.write)
+	}
+	r.index(r.base, before, after, writes, created)
+}
+
+func (r *shardedIpConfigRepository) Append(ipConfig ...models.IpConfig) errorlib.Error {
+	_, svcErr := r.updateShards(ipConfig, func(_ int, existingInfo []models.IpConfig, ipConfig []models.IpConfig) []models.IpConfig {
+		return append(existingInfo, ipConfig...)
+	})
+	return svcErr
+}
+
+func (r *shardedIpConfigRepository) Upsert(ipConfig ...models.IpConfig) (models.UpsertResult, errorlib.Error) {
+	var mu sync.Mutex
+	results := make(map[int]models.UpsertResult)
+	count, svcErr := r.updateShards(ipConfig, func(shard int, existingInfo []models.IpConfig, ipConfig []models.IpConfig) []models.IpConfig {
+		// recomputed on every attempt as the existing data may have changed
+		merged, result := upsertRecords(existingInfo, ipConfig)
+		mu.Lock()
+		results[shard] = result
+		mu.Unlock()
+		return merged
+	})
+	if svcErr != nil {
+		return models.UpsertResult{}, svcErr
+	}
+	// results of every shard are in input order, merge them back into overall input order
+	var result models.UpsertResult
+	next := make(map[int]int)
+	for _, record := range ipConfig {
+		i := shardOf(record.Ip, count)
+		result.Results = append(result.Results, results[i].Results[next[i]])
+		next[i]++
+	}
+	for i := 0; i < count; i++ {
+		result.Duplicates = append(result.Duplicates, results[i].Duplicates...)
+	}
+	if len(result.Duplicates) > 0 {
+		log.Printf("merged duplicated IPs %v", result.Duplicates)
+	}
+	return result, nil
+}
+
+// read all shards, modify the records and write back the shards whose records changed. If a shard
+// was written by someone else in between, the update is retried if no shard was written yet.
+// Otherwise, as shards cannot be written atomically and modify cannot be applied twice to the
+// written shards, the update fails and the conflicting shards are recorded as needing repair
+func (r *shardedIpConfigRepository) Update(modify func([]models.IpConfig) ([]models.IpConfig, error)) errorlib.Error {
+	for attempt := 1; ; attempt++ {
+		ipConfig, written, err := r.update(modify)
+		if err == nil {
+			r.snapshot(r.base, ipConfig)
+			return nil
+		}
+		if !errors.Is(err, errConflict) || written || attempt == updateAttempts {
+			log.Printf("%v", err)
+			return toError(err)
+		}
+	}
+}
+
+// single attempt of Update. Returns the records of all shards after the write and whether any
+// shard was written
+func (r *shardedIpConfigRepository) update(modify func([]models.IpConfig) ([]models.IpConfig, error)) ([]models.IpConfig, bool, error) {
+	m, created, err := r.manifest(true)
+	if err != nil {
+		return nil, false, err
+	}
+	current := make([][]byte, len(m.Shards))
+	exists := make([]bool, len(m.Shards))
+	shards := make([][]models.IpConfig, len(m.Shards))
+	err = forEach(len(m.Shards), r.concurrency, func(i int) error {
+		var err error
+		current[i], exists[i], err = r.getShard(m.Shards[i])
+		if err != nil {
+			return err
+		}
+		shards[i], err = decodeShard(current[i])
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	var existingInfo []models.IpConfig
+	for _, shard := range shards {
+		existingInfo = append(existingInfo, shard...)
+	}
+	ipConfig, err := modify(existingInfo)
+	if err != nil {
+		return nil, false, err
+	}
+	modified := partition(ipConfig, len(m.Shards))
+	changes := make([]shardChange, len(m.Shards))
+	conflicts := make([]bool, len(m.Shards))
+	var mu sync.Mutex
+	written := false
+	err = forEach(len(m.Shards), r.concurrency, func(i int) error {
+		data, err := json.Marshal(modified[i])
+		if err != nil {
+			return err
+		}
+		// shards which were not written yet and stay empty are left missing
+		if bytes.Equal(data, current[i]) || (!exists[i] && len(modified[i]) == 0) {
+			return nil
+		}
+		err = r.store.Update(m.Shards[i], func(latest []byte, latestExists bool) ([]byte, error) {
+			if latestExists != exists[i] || !bytes.Equal(latest, current[i]) {
+				return nil, errConflict
+			}
+			return data, nil
+		})
+		if errors.Is(err, errConflict) {
+			conflicts[i] = true
+			return nil
+		}
+		if err == nil {
+			// records were decoded again from the read data as modify may change them in place
+			before, _ := decodeShard(current[i])
+			write := ObjectWrite{Key: m.Shards[i], Before: objectVersion(current[i], exists[i]), After: objectVersion(data, true)}
+			changes[i] = shardChange{before: before, after: modified[i], write: write}
+			mu.Lock()
+			written = true
+			mu.Unlock()
+		}
+		return err
+	})
+	hasConflicts := false
+	for _, conflict := range conflicts {
+		hasConflicts = hasConflicts || conflict
+	}
+	if err == nil && hasConflicts {
+		err = errConflict
+		if written {
+			var keys []string
+			for i, conflict := range conflicts {
+				if conflict {
+					keys = append(keys, m.Shards[i])
+				}
+			}
+			err = errPartialConflict
+			if repairErr := r.markRepair(keys); repairErr != nil {
+				log.Printf("%v", repairErr)
+			}
+			log.Printf("update was not written to shards %v, they need repair", keys)
+		}
+	}
+	r.indexShards(changes, created)
+	var result []models.IpConfig
+	for _, shard := range modified {
+		result = append(result, shard...)
+	}
+	return result, written, err
+}
+
+// add shards to the repair list of the manifest
+func (r *shardedIpConfigRepository) markRepair(keys []string) error {
+	return r.store.Update(r.manifestKey(), func(data []byte, exists bool) ([]byte, error) {
+		if !exists {
+			return nil, errors.New("manifest " + r.manifestKey() + " not found")
+		}
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if !slices.Contains(m.Repair, key) {
+				m.Repair = append(m.Repair, key)
+			}
+		}
+		return json.Marshal(m)
+	})
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/mta-hosting-optimizer/lib/storage"
+)
+
+// layout of snapshot ids, ids sort in the order snapshots were taken
+const snapshotIdLayout = "20060102T150405.000000000Z"
+
+// storage of timestamped copies of the server data
+type SnapshotRepository interface {
+	// store records as snapshot taken at the given time, deleting snapshots older than the retention
+	Save(takenAt time.Time, ipConfig []models.IpConfig) errorlib.Error
+	// get all snapshots, oldest first
+	List() ([]models.Snapshot, errorlib.Error)
+	// get records of a snapshot
+	Load(id string) ([]models.IpConfig, errorlib.Error)
+}
+
+// repository keeping every snapshot as a JSON array in its own object below a key prefix.
+// Snapshots are kept for the retention, counted back from the latest snapshot. 0 keeps all snapshots
+type snapshotRepository struct {
+	store     storage.Store
+	prefix    string
+	retention time.Duration
+}
+
+func NewSnapshotRepository(store storage.Store, prefix string, retention time.Duration) SnapshotRepository {
+	return &snapshotRepository{store: store, prefix: prefix, retention: retention}
+}
+
+func (r *snapshotRepository) Save(takenAt time.Time, ipConfig []models.IpConfig) errorlib.Error {
+	ipConfigBytes, err := json.Marshal(ipConfig)
+	if err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	if err := r.store.Put(r.key(takenAt.UTC().Format(snapshotIdLayout)), ipConfigBytes); err != nil {
+		log.Printf("%v", err)
+		return errorlib.New(err, http.StatusInternalServerError)
+	}
+	return r.prune(takenAt)
+}
+
+// delete snapshots taken longer than retention before takenAt, however many snapshots were taken since
+func (r *snapshotRepository) prune(takenAt time.Time) errorlib.Error {
+	if r.retention == 0 {
+		return nil
+	}
+	snapshots, svcErr := r.List()
+	if svcErr != nil {
+		return svcErr
+	}
+	cutoff := takenAt.Add(-r.retention)
+	for _, snapshot := range snapshots {
+		// snapshots are listed oldest first
+		if !snapshot.TakenAt.Before(cutoff) {
+			break
+		}
+		if err := r.store.Delete(r.key(snapshot.Id)); err != nil {
+			log.Printf("%v", err)
+			return errorlib.New(err, http.StatusInternalServerError)
+		}
+	}
+	return nil
+}
+
+func (r *snapshotRepository) List() ([]models.Snapshot, errorlib.Error) {
+	keys, err := r.store.List(r.prefix)
+	if err != nil {
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	snapshots := []models.Snapshot{}
+	for _, key := range keys {
+		id := strings.TrimSuffix(strings.TrimPrefix(key, r.prefix), ".json")
+		// other objects below the prefix are not snapshots
+		takenAt, err := time.Parse(snapshotIdLayout, id)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, models.Snapshot{Id: id, TakenAt: takenAt})
+	}
+	return snapshots, nil
+}
+
+func (r *snapshotRepository) Load(id string) ([]models.IpConfig, errorlib.Error) {
+	if _, err := time.Parse(snapshotIdLayout, id); err != nil {
+		return nil, errorlib.New(errors.New("invalid snapshot id "+id), http.StatusBadRequest)
+	}
+	ipConfigBytes, err := r.store.Get(r.key(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errorlib.New(errors.New("snapshot not found for "+id), http.StatusNotFound)
+		}
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	var ipConfig []models.IpConfig
+	if err := json.Unmarshal(ipConfigBytes, &ipConfig); err != nil {
+		log.Printf("%v", err)
+		return nil, errorlib.New(err, http.StatusInternalServerError)
+	}
+	return ipConfig, nil
+}
+
+func (r *snapshotRepository) key(id string) string {
+	return r.prefix + id + ".json"
+}
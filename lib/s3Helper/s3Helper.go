@@ -2,15 +2,26 @@ package s3helper
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	s3Svc "github.com/aws/aws-sdk-go/service/s3"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
 	"github.com/mta-hosting-optimizer/lib/service"
 )
 
+// attempts and base backoff of conditional updates
+var (
+	MaxUpdateAttempts = 5
+	RetryDelay        = 50 * time.Millisecond
+)
+
 // add data to file in s3 bucket
 func PutS3Object(svc service.Service, byteData []byte, bucket string, key string) error {
 	params := &s3Svc.PutObjectInput{
@@ -26,8 +37,27 @@ func PutS3Object(svc service.Service, byteData []byte, bucket string, key string
 	return nil
 }
 
+// delete file from s3 bucket, deleting a missing file succeeds
+func DeleteS3Object(svc service.Service, bucket string, key string) error {
+	_, err := svc.S3.DeleteObject(&s3Svc.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("%v", err)
+		return err
+	}
+	return nil
+}
+
 // get data from file in s3 bucket
 func GetS3Object(svc service.Service, bucket string, key string) ([]byte, error) {
+	byteData, _, err := getS3ObjectWithETag(svc, bucket, key)
+	return byteData, err
+}
+
+// open data of file in s3 bucket for reading without loading it into memory. The caller must close the reader
+func GetS3ObjectReader(svc service.Service, bucket string, key string) (io.ReadCloser, error) {
 	params := &s3Svc.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
@@ -37,19 +67,96 @@ func GetS3Object(svc service.Service, bucket string, key string) ([]byte, error)
 		log.Printf("%v", err)
 		return nil, err
 	}
+	return result.Body, nil
+}
+
+// get data and ETag of file in s3 bucket
+func getS3ObjectWithETag(svc service.Service, bucket string, key string) ([]byte, string, error) {
+	params := &s3Svc.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	result, err := svc.S3.GetObject(params)
+	if err != nil {
+		log.Printf("%v", err)
+		return nil, "", err
+	}
 	defer result.Body.Close()
 
 	// capture all bytes from upload
 	byteData, err := io.ReadAll(result.Body)
 	if err != nil {
 		log.Printf("%v", err)
-		return nil, err
+		return nil, "", err
 	}
 
-	return byteData, nil
+	return byteData, aws.StringValue(result.ETag), nil
 
 }
 
+// add data to file in s3 bucket only if it was not changed since it was read.
+// An empty ETag means the file must not exist yet
+func putS3ObjectIfMatch(svc service.Service, byteData []byte, bucket string, key string, etag string) error {
+	params := &s3Svc.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   aws.ReadSeekCloser(bytes.NewReader(byteData)),
+	}
+	condition := map[string]string{"If-None-Match": "*"}
+	if etag != "" {
+		condition = map[string]string{"If-Match": etag}
+	}
+	_, err := svc.S3.PutObjectWithContext(aws.BackgroundContext(), params, request.WithSetRequestHeaders(condition))
+	return err
+}
+
+// read, modify and write file in s3 bucket using conditional writes on the ETag of the read data.
+// If the file was changed concurrently the update is retried on fresh data, and a conflict error
+// is returned once all attempts are exhausted
+func UpdateS3Object(svc service.Service, bucket string, key string, update func(byteData []byte, exists bool) ([]byte, error)) error {
+	for attempt := 1; attempt <= MaxUpdateAttempts; attempt++ {
+		var byteData []byte
+		var etag string
+		exists, err := KeyExists(svc, bucket, key)
+		if err != nil {
+			log.Printf("%v", err)
+			return err
+		}
+		if exists {
+			byteData, etag, err = getS3ObjectWithETag(svc, bucket, key)
+			if err != nil {
+				return err
+			}
+		}
+		newData, err := update(byteData, exists)
+		if err != nil {
+			return err
+		}
+		err = putS3ObjectIfMatch(svc, newData, bucket, key, etag)
+		if err == nil {
+			return nil
+		}
+		if !isConflict(err) {
+			log.Printf("%v", err)
+			return err
+		}
+		log.Printf("conflicting write to %s/%s on attempt %d: %v", bucket, key, attempt, err)
+		time.Sleep(time.Duration(attempt) * RetryDelay)
+	}
+	return errorlib.New(errors.New("data was modified concurrently. Please retry the request"), http.StatusConflict)
+}
+
+// check if write failed because the file was modified by another writer
+func isConflict(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return true
+		}
+	}
+	return false
+}
+
 // check if file exist in s3 bucket
 func KeyExists(svc service.Service, bucket string, key string) (bool, error) {
 	_, err := svc.S3.HeadObject(&s3Svc.HeadObjectInput{
@@ -57,15 +164,44 @@ func KeyExists(svc service.Service, bucket string, key string) (bool, error) {
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NotFound":
-				return false, nil
-			default:
-				return false, err
-			}
+		if IsNotFound(err) {
+			return false, nil
 		}
 		return false, err
 	}
 	return true, nil
 }
+
+// check if request failed because the file does not exist
+func IsNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "NotFound", "NoSuchKey":
+			return true
+		}
+	}
+	return false
+}
+
+// get keys of files in s3 bucket starting with prefix, in lexicographical order
+func ListS3Keys(svc service.Service, bucket string, prefix string) ([]string, error) {
+	var keys []string
+	params := &s3Svc.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		output, err := svc.S3.ListObjectsV2(params)
+		if err != nil {
+			log.Printf("%v", err)
+			return nil, err
+		}
+		for _, object := range output.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			return keys, nil
+		}
+		params.ContinuationToken = output.NextContinuationToken
+	}
+}
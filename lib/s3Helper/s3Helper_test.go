@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"net/http"
+	"strconv"
+	"sync"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	dummyS3 "github.com/mta-hosting-optimizer/lib/aws/s3/dummy"
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
 	"github.com/mta-hosting-optimizer/lib/service"
 	"github.com/stretchr/testify/assert"
 )
@@ -59,6 +66,25 @@ func Test_GetS3Object_Success(t *testing.T) {
 	assert.Equal(t, result, []byte("Dummy Data"))
 
 }
+func Test_GetS3ObjectReader_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString("Dummy Data")),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	reader, err := GetS3ObjectReader(svc, "dummy", "dummy")
+	assert.Nil(t, err)
+	defer reader.Close()
+	result, _ := io.ReadAll(reader)
+	assert.Equal(t, result, []byte("Dummy Data"))
+}
+
 func Test_GetS3Object_Failure(t *testing.T) {
 	sess, _ := session.NewSession()
 	svc := service.Service{
@@ -90,18 +116,286 @@ func Test_KeyExists_Success(t *testing.T) {
 	assert.Equal(t, result, true)
 
 }
+func Test_KeyExists_NotFound_Success(t *testing.T) {
+	for _, code := range []string{"NotFound", "NoSuchKey"} {
+		t.Run(code, func(t *testing.T) {
+			sess, _ := session.NewSession()
+			svc := service.Service{
+				S3: dummyS3.S3Interface{
+					DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+						return &s3.HeadObjectOutput{}, awserr.New(code, "not found", nil)
+					},
+				},
+				Sess: sess,
+			}
+			result, err := KeyExists(svc, "dummy", "dummy")
+			assert.Nil(t, err)
+			assert.Equal(t, result, false)
+		})
+	}
+}
+
 func Test_KeyExists_Failure(t *testing.T) {
 	sess, _ := session.NewSession()
 	svc := service.Service{
 		S3: dummyS3.S3Interface{
 			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
-				return &s3.HeadObjectOutput{}, errors.New("NotFound")
+				return &s3.HeadObjectOutput{}, awserr.New("SlowDown", "please reduce your request rate", nil)
 			},
 		},
 		Sess: sess,
 	}
 	result, err := KeyExists(svc, "dummy", "dummy")
-	assert.Equal(t, err.Error(), "NotFound")
+	assert.Equal(t, err.Error(), "SlowDown: please reduce your request rate")
 	assert.Equal(t, result, false)
 
 }
+
+// get conditional headers set by request options
+func requestHeaders(opts ...request.Option) http.Header {
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	r.ApplyOptions(opts...)
+	return r.HTTPRequest.Header
+}
+
+func Test_UpdateS3Object_Success(t *testing.T) {
+	var headers http.Header
+	var putData []byte
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString("Dummy Data")),
+					ETag: aws.String(`"etag1"`),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				headers = requestHeaders(opts...)
+				putData, _ = io.ReadAll(input.Body)
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	err := UpdateS3Object(svc, "dummy", "dummy", func(byteData []byte, exists bool) ([]byte, error) {
+		assert.True(t, exists)
+		return append(byteData, " Updated"...), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, headers.Get("If-Match"), `"etag1"`)
+	assert.Equal(t, putData, []byte("Dummy Data Updated"))
+}
+
+func Test_UpdateS3Object_NewObject_Success(t *testing.T) {
+	var headers http.Header
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				headers = requestHeaders(opts...)
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	err := UpdateS3Object(svc, "dummy", "dummy", func(byteData []byte, exists bool) ([]byte, error) {
+		assert.False(t, exists)
+		return []byte("Dummy Data"), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, headers.Get("If-None-Match"), "*")
+	assert.Equal(t, headers.Get("If-Match"), "")
+}
+
+func Test_UpdateS3Object_ConcurrentWrite_Success(t *testing.T) {
+	RetryDelay = 0
+	// object changes between the first read and write, as if another writer won the race
+	var mu sync.Mutex
+	stored := "Dummy Data"
+	etag := 1
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString(stored)),
+					ETag: aws.String(strconv.Itoa(etag)),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if requestHeaders(opts...).Get("If-Match") != strconv.Itoa(etag) {
+					return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+				}
+				data, _ := io.ReadAll(input.Body)
+				stored = string(data)
+				etag++
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	attempts := 0
+	err := UpdateS3Object(svc, "dummy", "dummy", func(byteData []byte, exists bool) ([]byte, error) {
+		attempts++
+		if attempts == 1 {
+			// concurrent writer updates the object after it was read
+			mu.Lock()
+			stored = "Concurrent Data"
+			etag++
+			mu.Unlock()
+		}
+		return append(byteData, " Updated"...), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, attempts, 2)
+	assert.Equal(t, stored, "Concurrent Data Updated")
+}
+
+func Test_UpdateS3Object_RetriesExhausted_Fail(t *testing.T) {
+	RetryDelay = 0
+	puts := 0
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString("Dummy Data")),
+					ETag: aws.String(`"etag1"`),
+				}, nil
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				puts++
+				return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+			},
+		},
+		Sess: sess,
+	}
+	err := UpdateS3Object(svc, "dummy", "dummy", func(byteData []byte, exists bool) ([]byte, error) {
+		return byteData, nil
+	})
+	assert.Equal(t, puts, MaxUpdateAttempts)
+	var svcErr errorlib.Error
+	assert.True(t, errors.As(err, &svcErr))
+	assert.Equal(t, svcErr.StatusCode(), 409)
+}
+
+func Test_UpdateS3Object_HeadObject_Fail(t *testing.T) {
+	puts := 0
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("SlowDown", "please reduce your request rate", nil)
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				puts++
+				return &s3.PutObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	err := UpdateS3Object(svc, "dummy", "dummy", func(byteData []byte, exists bool) ([]byte, error) {
+		return []byte("Dummy Data"), nil
+	})
+	assert.Equal(t, err.Error(), "SlowDown: please reduce your request rate")
+	assert.Equal(t, puts, 0)
+}
+
+func Test_UpdateS3Object_PutObject_Fail(t *testing.T) {
+	puts := 0
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+			DummyPutObjectWithContext: func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+				puts++
+				return nil, errors.New("put object failure")
+			},
+		},
+		Sess: sess,
+	}
+	err := UpdateS3Object(svc, "dummy", "dummy", func(byteData []byte, exists bool) ([]byte, error) {
+		return []byte("Dummy Data"), nil
+	})
+	assert.Equal(t, err.Error(), "put object failure")
+	assert.Equal(t, puts, 1)
+}
+
+func Test_ListS3Keys_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	var tokens []string
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyListObjectsV2: func(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+				tokens = append(tokens, aws.StringValue(input.ContinuationToken))
+				// return two pages
+				if input.ContinuationToken == nil {
+					return &s3.ListObjectsV2Output{
+						Contents:              []*s3.Object{{Key: aws.String("dummy/1")}, {Key: aws.String("dummy/2")}},
+						IsTruncated:           aws.Bool(true),
+						NextContinuationToken: aws.String("page2"),
+					}, nil
+				}
+				return &s3.ListObjectsV2Output{
+					Contents:    []*s3.Object{{Key: aws.String("dummy/3")}},
+					IsTruncated: aws.Bool(false),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	result, err := ListS3Keys(svc, "dummy", "dummy/")
+	assert.Nil(t, err)
+	assert.Equal(t, result, []string{"dummy/1", "dummy/2", "dummy/3"})
+	assert.Equal(t, tokens, []string{"", "page2"})
+}
+
+func Test_ListS3Keys_Failure(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyListObjectsV2: func(*s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+				return nil, errors.New("list objects failed")
+			},
+		},
+		Sess: sess,
+	}
+	_, err := ListS3Keys(svc, "dummy", "dummy/")
+	assert.Equal(t, err.Error(), "list objects failed")
+}
+
+func Test_DeleteS3Object_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	var keys []string
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyDeleteObject: func(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+				keys = append(keys, aws.StringValue(input.Key))
+				return &s3.DeleteObjectOutput{}, nil
+			},
+		},
+		Sess: sess,
+	}
+	err := DeleteS3Object(svc, "dummy", "dummy/1")
+	assert.Nil(t, err)
+	assert.Equal(t, keys, []string{"dummy/1"})
+}
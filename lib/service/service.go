@@ -1,50 +1,168 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 
 	"github.com/mta-hosting-optimizer/lib/aws/s3"
+	"github.com/mta-hosting-optimizer/lib/codec"
+	"github.com/mta-hosting-optimizer/lib/config"
 	"github.com/mta-hosting-optimizer/lib/constants"
 	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
 	"github.com/mta-hosting-optimizer/lib/models"
 )
 
+// lambda handler for API Gateway HTTP API events
+type Handler func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
 type Service struct {
-	Sess *session.Session
-	S3   s3.Interface
+	Sess   *session.Session
+	S3     s3.Interface
+	Config config.Config
 }
 
-func NewService() (Service, error) {
+func NewService(cfg config.Config) (Service, error) {
 	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(constants.Region)},
+		Region: aws.String(cfg.Region)},
 	)
 	if err != nil {
 		return Service{}, err
 	}
 	s3Client := s3.NewService(sess)
 	return Service{
-		Sess: sess,
-		S3:   s3Client,
+		Sess:   sess,
+		S3:     s3Client,
+		Config: cfg,
 	}, nil
 }
 
-func SuccessResponse(resp models.ServerResponse) events.APIGatewayV2HTTPResponse {
+func SuccessResponse(resp interface{}) events.APIGatewayV2HTTPResponse {
+	return JSONResponse(http.StatusOK, resp)
+}
+
+func JSONResponse(statusCode int, resp interface{}) events.APIGatewayV2HTTPResponse {
 	respBytes, _ := json.Marshal(resp)
 	return events.APIGatewayV2HTTPResponse{
 		Body:       string(respBytes),
-		StatusCode: http.StatusOK,
+		StatusCode: statusCode,
+	}
+}
+
+// get threshold from query parameter, falling back to the configured threshold
+func (svc Service) GetThreshold(req events.APIGatewayV2HTTPRequest) (int, errorlib.Error) {
+	value, ok := req.QueryStringParameters[constants.ThresholdKey]
+	if !ok {
+		return svc.Config.Threshold, nil
+	}
+	threshold, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || threshold < 0 {
+		return 0, errorlib.New(errors.New("invalid threshold query parameter. Please provide a non-negative integer"), http.StatusBadRequest)
+	}
+	return int(threshold), nil
+}
+
+// get rule marking servers as inefficient from query parameters. Criteria set in the request replace
+// the configured criteria, match overrides the configured way of combining them
+func (svc Service) GetRule(req events.APIGatewayV2HTTPRequest) (models.Rule, errorlib.Error) {
+	params := req.QueryStringParameters
+	rule := svc.Config.Rule()
+	_, hasThreshold := params[constants.ThresholdKey]
+	ratioValue, hasRatio := params[constants.RatioKey]
+	minIpsValue, hasMinIps := params[constants.MinIpsKey]
+	if hasThreshold || hasRatio || hasMinIps {
+		rule = models.Rule{Match: rule.Match}
+	}
+	if hasThreshold {
+		threshold, svcErr := svc.GetThreshold(req)
+		if svcErr != nil {
+			return models.Rule{}, svcErr
+		}
+		rule.MaxActive = &threshold
+	}
+	if hasRatio {
+		ratio, err := strconv.ParseFloat(ratioValue, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return models.Rule{}, errorlib.New(errors.New("invalid ratio query parameter. Please provide a number between 0 and 1"), http.StatusBadRequest)
+		}
+		rule.MaxActiveRatio = &ratio
+	}
+	if hasMinIps {
+		minIps, err := strconv.Atoi(minIpsValue)
+		if err != nil || minIps < 0 {
+			return models.Rule{}, errorlib.New(errors.New("invalid minIps query parameter. Please provide a non-negative integer"), http.StatusBadRequest)
+		}
+		rule.MinTotalIps = &minIps
+	}
+	if match, ok := params[constants.MatchKey]; ok {
+		if match != models.MatchAll && match != models.MatchAny {
+			return models.Rule{}, errorlib.New(errors.New("invalid match query parameter. Please provide and or or"), http.StatusBadRequest)
+		}
+		rule.Match = match
+	}
+	return rule, nil
+}
+
+// response with a body in a format other than JSON
+func ContentResponse(statusCode int, format string, body []byte) events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{
+		Body:       string(body),
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": codec.ContentType(format)},
+	}
+}
+
+// get request body, decoding binary payloads which API Gateway passes base64 encoded. Bodies
+// declared as ISO-8859-1 by the content type are converted to UTF-8
+func GetBody(req events.APIGatewayV2HTTPRequest) ([]byte, errorlib.Error) {
+	if req.Body == "" {
+		return nil, errorlib.New(errors.New("request body cannot be empty. Please provide valid data"), http.StatusBadRequest)
+	}
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, errorlib.New(errors.New("malformed request body: invalid base64 encoding"), http.StatusBadRequest)
+		}
+	}
+	if _, params, err := mime.ParseMediaType(req.Headers["content-type"]); err == nil {
+		switch strings.ToLower(params["charset"]) {
+		case "iso-8859-1", "latin1":
+			// every byte is the code point of the same value
+			runes := make([]rune, len(body))
+			for i, b := range body {
+				runes[i] = rune(b)
+			}
+			body = []byte(string(runes))
+		}
 	}
+	return body, nil
+}
+
+// get response format from format query parameter, falling back to the accept header
+func GetFormat(req events.APIGatewayV2HTTPRequest) (string, errorlib.Error) {
+	format, err := codec.Negotiate(req.QueryStringParameters[constants.FormatKey], req.Headers["accept"])
+	if err != nil {
+		return "", errorlib.New(errors.New("invalid format query parameter. Please provide json, csv or ndjson"), http.StatusBadRequest)
+	}
+	return format, nil
 }
 
 func ErrorResponse(errResp errorlib.Error) events.APIGatewayV2HTTPResponse {
 	respBytes, _ := json.Marshal(models.ErrorResponse{
-		Error: errResp.Error(),
-		Code:  errResp.StatusCode(),
+		Error:   errResp.Error(),
+		Code:    errResp.StatusCode(),
+		Details: errResp.Details(),
 	})
 	return events.APIGatewayV2HTTPResponse{
 		Body:       string(respBytes),
@@ -52,14 +170,15 @@ func ErrorResponse(errResp errorlib.Error) events.APIGatewayV2HTTPResponse {
 	}
 }
 
-func MockSuccessResponse() events.APIGatewayV2HTTPResponse {
-	respBytes, _ := json.Marshal(struct {
-		Message string `json:"message"`
-	}{
-		Message: "Success",
-	})
+func MockSuccessResponse(resp models.IngestResponse) events.APIGatewayV2HTTPResponse {
+	resp.Message = "Success"
+	statusCode := http.StatusOK
+	if resp.Created > 0 {
+		statusCode = http.StatusCreated
+	}
+	respBytes, _ := json.Marshal(resp)
 	return events.APIGatewayV2HTTPResponse{
 		Body:       string(respBytes),
-		StatusCode: http.StatusCreated,
+		StatusCode: statusCode,
 	}
 }
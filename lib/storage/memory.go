@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// store keeping objects in process memory
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *memoryStore) Open(key string) (io.ReadCloser, error) {
+	data, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStore) Put(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memoryStore) Update(key string, update UpdateFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, exists := s.objects[key]
+	newData, err := update(append([]byte(nil), data...), exists)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = append([]byte(nil), newData...)
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryStore) List(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
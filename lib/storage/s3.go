@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"io"
+
+	s3helper "github.com/mta-hosting-optimizer/lib/s3Helper"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+// store keeping objects in a S3 bucket
+type s3Store struct {
+	svc    service.Service
+	bucket string
+}
+
+func NewS3Store(svc service.Service, bucket string) Store {
+	return &s3Store{svc: svc, bucket: bucket}
+}
+
+func (s *s3Store) Get(key string) ([]byte, error) {
+	exist, err := s3helper.KeyExists(s.svc, s.bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, ErrNotFound
+	}
+	data, err := s3helper.GetS3Object(s.svc, s.bucket, key)
+	if s3helper.IsNotFound(err) {
+		// deleted after the existence check
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *s3Store) Open(key string) (io.ReadCloser, error) {
+	exist, err := s3helper.KeyExists(s.svc, s.bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, ErrNotFound
+	}
+	reader, err := s3helper.GetS3ObjectReader(s.svc, s.bucket, key)
+	if s3helper.IsNotFound(err) {
+		// deleted after the existence check
+		return nil, ErrNotFound
+	}
+	return reader, err
+}
+
+func (s *s3Store) Put(key string, data []byte) error {
+	return s3helper.PutS3Object(s.svc, data, s.bucket, key)
+}
+
+func (s *s3Store) Update(key string, update UpdateFunc) error {
+	return s3helper.UpdateS3Object(s.svc, s.bucket, key, update)
+}
+
+func (s *s3Store) Delete(key string) error {
+	return s3helper.DeleteS3Object(s.svc, s.bucket, key)
+}
+
+func (s *s3Store) List(prefix string) ([]string, error) {
+	return s3helper.ListS3Keys(s.svc, s.bucket, prefix)
+}
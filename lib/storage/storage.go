@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/service"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// key-value object storage holding the service data
+type Store interface {
+	// get object data, returns ErrNotFound if the object does not exist
+	Get(key string) ([]byte, error)
+	// open object data for reading without loading it into memory, returns ErrNotFound if the
+	// object does not exist. The caller must close the reader
+	Open(key string) (io.ReadCloser, error)
+	// create or replace object data
+	Put(key string, data []byte) error
+	// atomically read, modify and write object data. Writes conflicting with concurrent
+	// updates are retried on fresh data
+	Update(key string, update UpdateFunc) error
+	// remove object, removing a missing object succeeds
+	Delete(key string) error
+	// get keys of objects starting with prefix, in lexicographical order
+	List(prefix string) ([]string, error)
+}
+
+// return new object data from current data. exists is false if the object does not exist yet
+type UpdateFunc func(data []byte, exists bool) ([]byte, error)
+
+// make store for the configured storage backend
+func New(svc service.Service) (Store, error) {
+	switch svc.Config.Storage {
+	case config.StorageS3:
+		return NewS3Store(svc, svc.Config.Bucket), nil
+	case config.StorageFile:
+		return NewFileStore(svc.Config.StoragePath), nil
+	case config.StorageMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", svc.Config.Storage)
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	dummyS3 "github.com/mta-hosting-optimizer/lib/aws/s3/dummy"
+	"github.com/mta-hosting-optimizer/lib/config"
+	"github.com/mta-hosting-optimizer/lib/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileStore_Success(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	_, err := store.Get("dummy/ipConfig.json")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Put("dummy/ipConfig.json", []byte("Dummy Data"))
+	assert.Nil(t, err)
+	result, err := store.Get("dummy/ipConfig.json")
+	assert.Nil(t, err)
+	assert.Equal(t, result, []byte("Dummy Data"))
+
+	err = store.Delete("dummy/ipConfig.json")
+	assert.Nil(t, err)
+	_, err = store.Get("dummy/ipConfig.json")
+	assert.ErrorIs(t, err, ErrNotFound)
+	// deleting a missing object succeeds
+	err = store.Delete("dummy/ipConfig.json")
+	assert.Nil(t, err)
+}
+
+func Test_MemoryStore_Success(t *testing.T) {
+	store := NewMemoryStore()
+	_, err := store.Get("ipConfig.json")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	data := []byte("Dummy Data")
+	err = store.Put("ipConfig.json", data)
+	assert.Nil(t, err)
+	// stored data must not change when caller reuses its buffer
+	data[0] = 'X'
+	result, err := store.Get("ipConfig.json")
+	assert.Nil(t, err)
+	assert.Equal(t, result, []byte("Dummy Data"))
+}
+
+func Test_S3Store_Success(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, nil
+			},
+			DummyGetObject: func(*s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewBufferString("Dummy Data")),
+				}, nil
+			},
+		},
+		Sess: sess,
+	}
+	result, err := NewS3Store(svc, "dummy").Get("dummy")
+	assert.Nil(t, err)
+	assert.Equal(t, result, []byte("Dummy Data"))
+}
+
+func Test_S3Store_NotFound_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("NotFound", "not found", nil)
+			},
+		},
+		Sess: sess,
+	}
+	result, err := NewS3Store(svc, "dummy").Get("dummy")
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_S3Store_HeadObject_Fail(t *testing.T) {
+	sess, _ := session.NewSession()
+	svc := service.Service{
+		S3: dummyS3.S3Interface{
+			DummyHeadObject: func(*s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				return &s3.HeadObjectOutput{}, awserr.New("SlowDown", "please reduce your request rate", nil)
+			},
+		},
+		Sess: sess,
+	}
+	store := NewS3Store(svc, "dummy")
+	result, err := store.Get("dummy")
+	assert.Nil(t, result)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, err.Error(), "SlowDown: please reduce your request rate")
+	reader, err := store.Open("dummy")
+	assert.Nil(t, reader)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+func Test_New_Success(t *testing.T) {
+	tests := []struct {
+		name     string
+		backend  string
+		expected Store
+	}{
+		{name: "S3 backend", backend: config.StorageS3, expected: &s3Store{svc: service.Service{Config: config.Config{Storage: config.StorageS3, Bucket: "dummy", StoragePath: "dummy"}}, bucket: "dummy"}},
+		{name: "File backend", backend: config.StorageFile, expected: &fileStore{dir: "dummy"}},
+		{name: "Memory backend", backend: config.StorageMemory, expected: NewMemoryStore()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.Service{Config: config.Config{Storage: tt.backend, Bucket: "dummy", StoragePath: "dummy"}}
+			result, err := New(svc)
+			assert.Nil(t, err)
+			assert.Equal(t, result, tt.expected)
+		})
+	}
+}
+
+func Test_New_UnsupportedBackend_Fail(t *testing.T) {
+	result, err := New(service.Service{Config: config.Config{Storage: "dummy"}})
+	assert.Nil(t, result)
+	assert.Equal(t, err.Error(), `unsupported storage backend "dummy"`)
+}
+
+func Test_FileStore_Update_Success(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	for i := 0; i < 3; i++ {
+		err := store.Update("ipConfig.json", func(data []byte, exists bool) ([]byte, error) {
+			assert.Equal(t, exists, i > 0)
+			return append(data, 'X'), nil
+		})
+		assert.Nil(t, err)
+	}
+	result, err := store.Get("ipConfig.json")
+	assert.Nil(t, err)
+	assert.Equal(t, result, []byte("XXX"))
+}
+
+func Test_MemoryStore_Update_Fail(t *testing.T) {
+	store := NewMemoryStore()
+	err := store.Update("ipConfig.json", func(data []byte, exists bool) ([]byte, error) {
+		return nil, errors.New("update failed")
+	})
+	assert.Equal(t, err.Error(), "update failed")
+	_, err = store.Get("ipConfig.json")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_List_Success(t *testing.T) {
+	tests := []struct {
+		name  string
+		store Store
+	}{
+		{name: "File store", store: NewFileStore(t.TempDir())},
+		{name: "Memory store", store: NewMemoryStore()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := tt.store.List("snapshots/")
+			assert.Nil(t, err)
+			assert.Nil(t, keys)
+
+			for _, key := range []string{"snapshots/2.json", "ipConfig.json", "snapshots/1.json"} {
+				tt.store.Put(key, []byte("Dummy Data"))
+			}
+			keys, err = tt.store.List("snapshots/")
+			assert.Nil(t, err)
+			assert.Equal(t, keys, []string{"snapshots/1.json", "snapshots/2.json"})
+		})
+	}
+}
+
+func Test_Open_Success(t *testing.T) {
+	stores := map[string]Store{
+		"file":   NewFileStore(t.TempDir()),
+		"memory": NewMemoryStore(),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Open("dummy/ipConfig.json")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			store.Put("dummy/ipConfig.json", []byte("Dummy Data"))
+			reader, err := store.Open("dummy/ipConfig.json")
+			assert.Nil(t, err)
+			result, _ := io.ReadAll(reader)
+			reader.Close()
+			assert.Equal(t, result, []byte("Dummy Data"))
+		})
+	}
+}
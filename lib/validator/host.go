@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// decode a JSON object into the metadata of the given host. The hostname field may be omitted,
+// if present it must equal the given hostname
+func DecodeHostForHostname(data []byte, hostname string) (models.Host, []errorlib.Detail) {
+	var host models.Host
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&host); err != nil {
+		return models.Host{}, []errorlib.Detail{{Message: "malformed host: " + err.Error()}}
+	}
+	var details []errorlib.Detail
+	if host.Hostname != "" && host.Hostname != hostname {
+		details = append(details, errorlib.Detail{Field: "hostname", Message: "hostname does not match hostname in path"})
+	}
+	host.Hostname = hostname
+	return host, append(details, ValidateHost(host)...)
+}
+
+// check hostname, capacity and tags of a host
+func ValidateHost(host models.Host) []errorlib.Detail {
+	var details []errorlib.Detail
+	if err := ValidateHostname(host.Hostname); err != nil {
+		details = append(details, errorlib.Detail{Field: "hostname", Message: err.Error()})
+	}
+	if host.Capacity < 0 {
+		details = append(details, errorlib.Detail{Field: "capacity", Message: "capacity cannot be negative"})
+	}
+	for i, tag := range host.Tags {
+		if tag == "" {
+			details = append(details, errorlib.Detail{Field: fmt.Sprintf("tags[%d]", i), Message: "tag cannot be empty"})
+		}
+	}
+	return details
+}
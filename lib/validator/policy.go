@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// decode a JSON array of policies, reporting unknown fields and every invalid policy
+func DecodePolicies(data []byte) ([]models.Policy, []errorlib.Detail) {
+	var policies []models.Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policies); err != nil || policies == nil {
+		message := "policies must be a JSON array"
+		if err != nil {
+			message = "malformed policies: " + err.Error()
+		}
+		return nil, []errorlib.Detail{{Message: message}}
+	}
+	return policies, ValidatePolicies(policies)
+}
+
+// check that policies have unique names, select hosts by a valid hostname pattern or tags
+// and have a valid rule
+func ValidatePolicies(policies []models.Policy) []errorlib.Detail {
+	var details []errorlib.Detail
+	names := make(map[string]bool, len(policies))
+	for i, policy := range policies {
+		field := func(name string) string {
+			return fmt.Sprintf("[%d].%s", i, name)
+		}
+		switch {
+		case policy.Name == "":
+			details = append(details, errorlib.Detail{Field: field("name"), Message: "name is required"})
+		case policy.Name == models.DefaultPolicy:
+			details = append(details, errorlib.Detail{Field: field("name"), Message: "name " + models.DefaultPolicy + " is reserved"})
+		case names[policy.Name]:
+			details = append(details, errorlib.Detail{Field: field("name"), Message: "name must be unique"})
+		}
+		names[policy.Name] = true
+		if policy.Hostname == "" && len(policy.Tags) == 0 {
+			details = append(details, errorlib.Detail{Field: field("hostname"), Message: "hostname or tags is required"})
+		}
+		if _, err := path.Match(policy.Hostname, ""); err != nil {
+			details = append(details, errorlib.Detail{Field: field("hostname"), Message: "hostname must be a valid glob pattern"})
+		}
+		for _, detail := range ValidateRule(policy.Rule) {
+			detail.Field = field("rule." + detail.Field)
+			details = append(details, detail)
+		}
+	}
+	return details
+}
+
+// check that rule has at least one criterion and valid values
+func ValidateRule(rule models.Rule) []errorlib.Detail {
+	var details []errorlib.Detail
+	if rule.MaxActive == nil && rule.MaxActiveRatio == nil && rule.MinTotalIps == nil {
+		details = append(details, errorlib.Detail{Field: "maxActive", Message: "at least one of maxActive, maxActiveRatio, minTotalIps is required"})
+	}
+	if rule.MaxActive != nil && *rule.MaxActive < 0 {
+		details = append(details, errorlib.Detail{Field: "maxActive", Message: "maxActive cannot be negative"})
+	}
+	if rule.MaxActiveRatio != nil && (*rule.MaxActiveRatio < 0 || *rule.MaxActiveRatio > 1) {
+		details = append(details, errorlib.Detail{Field: "maxActiveRatio", Message: "maxActiveRatio must be between 0 and 1"})
+	}
+	if rule.MinTotalIps != nil && *rule.MinTotalIps < 0 {
+		details = append(details, errorlib.Detail{Field: "minTotalIps", Message: "minTotalIps cannot be negative"})
+	}
+	if rule.Match != "" && rule.Match != models.MatchAll && rule.Match != models.MatchAny {
+		details = append(details, errorlib.Detail{Field: "match", Message: "match must be and or or"})
+	}
+	return details
+}
@@ -0,0 +1,235 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+)
+
+// record fields, as named in JSON
+const (
+	ipField       = "ip"
+	hostnameField = "hostname"
+	activeField   = "active"
+)
+
+// decode a JSON object into a record with its IP in canonical form. Every violation is reported: malformed JSON,
+// unknown fields, missing fields, wrong field types, invalid IP addresses and hostnames
+func DecodeIpConfig(data []byte) (models.IpConfig, []errorlib.Detail) {
+	return decodeIpConfig(data, "")
+}
+
+// decode and validate every raw record, returning the violations of all invalid records
+// with fields qualified by the index of their record, e.g. [1].hostname
+func DecodeIpConfigs(rawRecords []json.RawMessage) ([]models.IpConfig, []errorlib.Detail) {
+	records := make([]models.IpConfig, 0, len(rawRecords))
+	var details []errorlib.Detail
+	for i, rawRecord := range rawRecords {
+		record, violations := DecodeIpConfig(rawRecord)
+		for _, detail := range violations {
+			detail.Field = recordField(i, detail.Field)
+			details = append(details, detail)
+		}
+		records = append(records, record)
+	}
+	return records, details
+}
+
+// qualify field with the index of its record
+func recordField(i int, field string) string {
+	if field == "" {
+		return fmt.Sprintf("[%d]", i)
+	}
+	return fmt.Sprintf("[%d].%s", i, field)
+}
+
+// decode a JSON object into the record of the given IP. The ip field may be omitted,
+// if present it must be the same address as the given IP
+func DecodeIpConfigForIp(data []byte, ip string) (models.IpConfig, []errorlib.Detail) {
+	return decodeIpConfig(data, ip)
+}
+
+// decode a JSON object into changes to a record, at least one field must be set
+func DecodeIpConfigPatch(data []byte) (models.IpConfigPatch, []errorlib.Detail) {
+	var patch models.IpConfigPatch
+	fields, details := decodeObject(data, hostnameField, activeField)
+	if fields == nil {
+		return patch, details
+	}
+	if raw, ok := fields[hostnameField]; ok {
+		var hostname string
+		if detail := decodeField(raw, hostnameField, &hostname, "hostname must be a string"); detail != nil {
+			details = append(details, *detail)
+		} else if err := ValidateHostname(hostname); err != nil {
+			details = append(details, errorlib.Detail{Field: hostnameField, Message: err.Error()})
+		} else {
+			patch.Hostname = &hostname
+		}
+	}
+	if raw, ok := fields[activeField]; ok {
+		var active bool
+		if detail := decodeField(raw, activeField, &active, "active must be true or false"); detail != nil {
+			details = append(details, *detail)
+		} else {
+			patch.Active = &active
+		}
+	}
+	if len(fields) == 0 {
+		details = append(details, errorlib.Detail{Message: "at least one of hostname, active is required"})
+	}
+	return patch, details
+}
+
+// check syntax of an IPv4 or IPv6 address
+func ValidateIp(ip string) error {
+	_, err := CanonicalIp(ip)
+	return err
+}
+
+// check syntax of an IPv4 or IPv6 address and return its canonical form, used as the key of records:
+// IPv6 in lower case with zeros compressed, IPv4-mapped IPv6 addresses as IPv4
+func CanonicalIp(ip string) (string, error) {
+	if ip == "" {
+		return "", errors.New("ip is required")
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		return "", errors.New("ip must be a valid IPv4 or IPv6 address")
+	}
+	return addr.Unmap().String(), nil
+}
+
+// canonical form of ip, or ip unchanged if it is not a valid address
+func NormalizeIp(ip string) string {
+	if canonical, err := CanonicalIp(ip); err == nil {
+		return canonical
+	}
+	return ip
+}
+
+// check hostname against RFC 1123: at most 253 characters of dot separated labels, each label
+// 1 to 63 letters, digits or hyphens, not starting or ending with a hyphen
+func ValidateHostname(hostname string) error {
+	if hostname == "" {
+		return errors.New("hostname is required")
+	}
+	if len(hostname) > 253 {
+		return errors.New("hostname must be at most 253 characters")
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if !isValidLabel(label) {
+			return errors.New("hostname must be a valid RFC 1123 hostname")
+		}
+	}
+	return nil
+}
+
+func isValidLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, c := range label {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeIpConfig(data []byte, pathIp string) (models.IpConfig, []errorlib.Detail) {
+	var record models.IpConfig
+	fields, details := decodeObject(data, ipField, hostnameField, activeField)
+	if fields == nil {
+		return record, details
+	}
+	if raw, ok := fields[ipField]; ok {
+		if detail := decodeField(raw, ipField, &record.Ip, "ip must be a string"); detail != nil {
+			details = append(details, *detail)
+		} else if pathIp != "" && NormalizeIp(record.Ip) != NormalizeIp(pathIp) {
+			details = append(details, errorlib.Detail{Field: ipField, Message: "ip does not match ip in path"})
+		}
+	} else {
+		record.Ip = pathIp
+	}
+	// a wrong type or mismatch is already reported for the ip field
+	if ip, err := CanonicalIp(record.Ip); err != nil {
+		if !hasField(details, ipField) {
+			details = append(details, errorlib.Detail{Field: ipField, Message: err.Error()})
+		}
+	} else {
+		record.Ip = ip
+	}
+	if raw, ok := fields[hostnameField]; ok {
+		if detail := decodeField(raw, hostnameField, &record.Hostname, "hostname must be a string"); detail != nil {
+			details = append(details, *detail)
+		} else if err := ValidateHostname(record.Hostname); err != nil {
+			details = append(details, errorlib.Detail{Field: hostnameField, Message: err.Error()})
+		}
+	} else {
+		details = append(details, errorlib.Detail{Field: hostnameField, Message: "hostname is required"})
+	}
+	if raw, ok := fields[activeField]; ok {
+		if detail := decodeField(raw, activeField, &record.Active, "active must be true or false"); detail != nil {
+			details = append(details, *detail)
+		}
+	} else {
+		details = append(details, errorlib.Detail{Field: activeField, Message: "active is required"})
+	}
+	return record, details
+}
+
+// decode JSON object into its raw fields, reporting malformed JSON and unknown fields.
+// Fields are nil if data is not a JSON object
+func decodeObject(data []byte, knownFields ...string) (map[string]json.RawMessage, []errorlib.Detail) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		message := "record must be a JSON object"
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			message = "malformed JSON: " + syntaxErr.Error()
+		}
+		return nil, []errorlib.Detail{{Message: message}}
+	}
+	known := make(map[string]bool, len(knownFields))
+	for _, field := range knownFields {
+		known[field] = true
+	}
+	var unknown []string
+	for field := range fields {
+		if !known[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	// report unknown fields in a stable order
+	sort.Strings(unknown)
+	var details []errorlib.Detail
+	for _, field := range unknown {
+		details = append(details, errorlib.Detail{Field: field, Message: "unknown field"})
+		delete(fields, field)
+	}
+	return fields, details
+}
+
+// decode a single field, null is treated as a wrong type
+func decodeField(raw json.RawMessage, field string, value interface{}, message string) *errorlib.Detail {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) || json.Unmarshal(raw, value) != nil {
+		return &errorlib.Detail{Field: field, Message: message}
+	}
+	return nil
+}
+
+func hasField(details []errorlib.Detail, field string) bool {
+	for _, detail := range details {
+		if detail.Field == field {
+			return true
+		}
+	}
+	return false
+}
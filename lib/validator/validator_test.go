@@ -0,0 +1,172 @@
+package validator
+
+import (
+	"encoding/json"
+	"testing"
+
+	errorlib "github.com/mta-hosting-optimizer/lib/errorLib"
+	"github.com/mta-hosting-optimizer/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateIp(t *testing.T) {
+	tests := []struct {
+		ip    string
+		valid bool
+	}{
+		{ip: "10.0.0.1", valid: true},
+		{ip: "2001:db8::1", valid: true},
+		{ip: "::ffff:10.0.0.1", valid: true},
+		{ip: "", valid: false},
+		{ip: "10.0.0", valid: false},
+		{ip: "10.0.0.256", valid: false},
+		{ip: "fe80::1%eth0", valid: false},
+		{ip: "DummyIP1", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, ValidateIp(tt.ip) == nil, tt.valid)
+		})
+	}
+}
+
+func Test_CanonicalIp(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{ip: "10.0.0.1", expected: "10.0.0.1"},
+		{ip: "2001:DB8::1", expected: "2001:db8::1"},
+		{ip: "2001:0db8:0000:0000:0000:0000:0000:0001", expected: "2001:db8::1"},
+		{ip: "::ffff:10.0.0.1", expected: "10.0.0.1"},
+		{ip: "::FFFF:0a00:0001", expected: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			result, err := CanonicalIp(tt.ip)
+			assert.Nil(t, err)
+			assert.Equal(t, result, tt.expected)
+		})
+	}
+	_, err := CanonicalIp("DummyIP1")
+	assert.Equal(t, err.Error(), "ip must be a valid IPv4 or IPv6 address")
+	assert.Equal(t, NormalizeIp("DummyIP1"), "DummyIP1")
+}
+
+func Test_ValidateHostname(t *testing.T) {
+	tests := []struct {
+		hostname string
+		valid    bool
+	}{
+		{hostname: "mta-prod-1.example.com", valid: true},
+		{hostname: "1host", valid: true},
+		{hostname: "", valid: false},
+		{hostname: "-host", valid: false},
+		{hostname: "host-", valid: false},
+		{hostname: "host..example", valid: false},
+		{hostname: "host.example.", valid: false},
+		{hostname: "host_name", valid: false},
+		{hostname: string(make([]byte, 64)), valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			assert.Equal(t, ValidateHostname(tt.hostname) == nil, tt.valid)
+		})
+	}
+}
+
+func Test_DecodeIpConfig_Success(t *testing.T) {
+	record, details := DecodeIpConfig([]byte(`{"ip":"10.0.0.1","hostname":"DummyHostname1","active":false}`))
+	assert.Nil(t, details)
+	assert.Equal(t, record, models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: false})
+}
+
+func Test_DecodeIpConfigs_Canonical_Success(t *testing.T) {
+	records, details := DecodeIpConfigs([]json.RawMessage{
+		[]byte(`{"ip":"2001:DB8::A","hostname":"DummyHostname1","active":true}`),
+		[]byte(`{"ip":"::ffff:10.0.0.1","hostname":"DummyHostname2","active":false}`),
+	})
+	assert.Nil(t, details)
+	assert.Equal(t, records, []models.IpConfig{
+		{Ip: "2001:db8::a", Hostname: "DummyHostname1", Active: true},
+		{Ip: "10.0.0.1", Hostname: "DummyHostname2", Active: false},
+	})
+}
+
+func Test_DecodeIpConfig_Fail(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		details []errorlib.Detail
+	}{
+		{
+			name:    "Malformed JSON",
+			data:    `{"ip":`,
+			details: []errorlib.Detail{{Message: "malformed JSON: unexpected end of JSON input"}},
+		},
+		{
+			name:    "Not an object",
+			data:    `["10.0.0.1"]`,
+			details: []errorlib.Detail{{Message: "record must be a JSON object"}},
+		},
+		{
+			name: "Missing fields",
+			data: `{}`,
+			details: []errorlib.Detail{
+				{Field: "ip", Message: "ip is required"},
+				{Field: "hostname", Message: "hostname is required"},
+				{Field: "active", Message: "active is required"},
+			},
+		},
+		{
+			name: "Wrong types and unknown fields",
+			data: `{"ip":1,"hostname":null,"active":"yes","zone":"a","port":25}`,
+			details: []errorlib.Detail{
+				{Field: "port", Message: "unknown field"},
+				{Field: "zone", Message: "unknown field"},
+				{Field: "ip", Message: "ip must be a string"},
+				{Field: "hostname", Message: "hostname must be a string"},
+				{Field: "active", Message: "active must be true or false"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, details := DecodeIpConfig([]byte(tt.data))
+			assert.Equal(t, details, tt.details)
+		})
+	}
+}
+
+func Test_DecodeIpConfigForIp(t *testing.T) {
+	record, details := DecodeIpConfigForIp([]byte(`{"hostname":"DummyHostname1","active":true}`), "10.0.0.1")
+	assert.Nil(t, details)
+	assert.Equal(t, record, models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+
+	record, details = DecodeIpConfigForIp([]byte(`{"ip":"2001:DB8::1","hostname":"DummyHostname1","active":true}`), "2001:db8:0::1")
+	assert.Nil(t, details)
+	assert.Equal(t, record, models.IpConfig{Ip: "2001:db8::1", Hostname: "DummyHostname1", Active: true})
+
+	record, details = DecodeIpConfigForIp([]byte(`{"hostname":"DummyHostname1","active":true}`), "::ffff:10.0.0.1")
+	assert.Nil(t, details)
+	assert.Equal(t, record, models.IpConfig{Ip: "10.0.0.1", Hostname: "DummyHostname1", Active: true})
+
+	_, details = DecodeIpConfigForIp([]byte(`{"ip":"10.0.0.2","hostname":"DummyHostname1","active":true}`), "10.0.0.1")
+	assert.Equal(t, details, []errorlib.Detail{{Field: "ip", Message: "ip does not match ip in path"}})
+}
+
+func Test_DecodeIpConfigPatch(t *testing.T) {
+	patch, details := DecodeIpConfigPatch([]byte(`{"active":false}`))
+	assert.Nil(t, details)
+	assert.Nil(t, patch.Hostname)
+	assert.Equal(t, *patch.Active, false)
+
+	_, details = DecodeIpConfigPatch([]byte(`{}`))
+	assert.Equal(t, details, []errorlib.Detail{{Message: "at least one of hostname, active is required"}})
+
+	_, details = DecodeIpConfigPatch([]byte(`{"ip":"10.0.0.1","hostname":"host_1"}`))
+	assert.Equal(t, details, []errorlib.Detail{
+		{Field: "ip", Message: "unknown field"},
+		{Field: "hostname", Message: "hostname must be a valid RFC 1123 hostname"},
+	})
+}